@@ -0,0 +1,105 @@
+package gospy
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Matches a single argument, when querying calls or asserting on them. Plain
+// values are compared with reflect.DeepEqual, as if wrapped by Eq()
+type ArgMatcher interface {
+	MatchesArg(arg interface{}) bool
+	String() string
+}
+
+// Implemented by Gomega matchers, which can be used wherever an ArgMatcher is expected
+type valueMatcher interface {
+	Match(actual interface{}) (success bool, err error)
+}
+
+type anyArgMatcher struct{}
+
+type eqMatcher struct {
+	expected interface{}
+}
+
+type predicateMatcher struct {
+	predicate func(arg interface{}) bool
+}
+
+type adaptedMatcher struct {
+	matcher valueMatcher
+}
+
+func AnyArg() ArgMatcher {
+	return anyArgMatcher{}
+}
+
+func Eq(expected interface{}) ArgMatcher {
+	return eqMatcher{expected}
+}
+
+func ArgThat(predicate func(arg interface{}) bool) ArgMatcher {
+	return predicateMatcher{predicate}
+}
+
+// Whether each argument matches the matcher or value at its position. Positions
+// past the last matcher match anything. Captors only match here, without capturing
+func ArgsMatch(args ArgList, matchers ...interface{}) bool {
+	if len(matchers) > len(args) {
+		return false
+	}
+
+	for i, matcher := range matchers {
+		if !ToArgMatcher(matcher).MatchesArg(args[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Wraps plain values with Eq(), and adapts Gomega matchers
+func ToArgMatcher(matcher interface{}) ArgMatcher {
+	switch matcher := matcher.(type) {
+	case ArgMatcher:
+		return matcher
+	case valueMatcher:
+		return adaptedMatcher{matcher}
+	default:
+		return Eq(matcher)
+	}
+}
+
+func (self anyArgMatcher) MatchesArg(interface{}) bool {
+	return true
+}
+
+func (self anyArgMatcher) String() string {
+	return "AnyArg()"
+}
+
+func (self eqMatcher) MatchesArg(arg interface{}) bool {
+	return reflect.DeepEqual(arg, self.expected)
+}
+
+func (self eqMatcher) String() string {
+	return FormatValue(self.expected)
+}
+
+func (self predicateMatcher) MatchesArg(arg interface{}) bool {
+	return self.predicate(arg)
+}
+
+func (self predicateMatcher) String() string {
+	return "ArgThat(" + FormatValue(self.predicate) + ")"
+}
+
+func (self adaptedMatcher) MatchesArg(arg interface{}) bool {
+	success, err := self.matcher.Match(arg)
+	return err == nil && success
+}
+
+func (self adaptedMatcher) String() string {
+	return fmt.Sprintf("%T", self.matcher)
+}
@@ -0,0 +1,57 @@
+package gospy_test
+
+import (
+	. "github.com/cfmobile/gospy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"strings"
+)
+
+var _ = Describe("Argument matchers", func() {
+	args := ArgList{"ann", 42, []string{"a", "b"}}
+
+	It("should compare plain values deeply", func() {
+		Expect(ArgsMatch(args, "ann", 42, []string{"a", "b"})).To(BeTrue())
+		Expect(ArgsMatch(args, "bob")).To(BeFalse())
+	})
+
+	It("should match anything with AnyArg", func() {
+		Expect(ArgsMatch(args, AnyArg(), 42)).To(BeTrue())
+	})
+
+	It("should match with Eq as with a plain value", func() {
+		Expect(ArgsMatch(args, Eq("ann"), Eq(42))).To(BeTrue())
+		Expect(ArgsMatch(args, Eq("bob"))).To(BeFalse())
+	})
+
+	It("should match arguments satisfying a predicate with ArgThat", func() {
+		startsWithA := ArgThat(func(arg interface{}) bool {
+			return strings.HasPrefix(arg.(string), "a")
+		})
+
+		Expect(ArgsMatch(args, startsWithA)).To(BeTrue())
+		Expect(ArgsMatch(ArgList{"bob"}, startsWithA)).To(BeFalse())
+	})
+
+	It("should accept Gomega matchers", func() {
+		Expect(ArgsMatch(args, HavePrefix("a"), BeNumerically(">", 40))).To(BeTrue())
+		Expect(ArgsMatch(args, HavePrefix("b"))).To(BeFalse())
+	})
+
+	It("should not match a Gomega matcher that errors", func() {
+		Expect(ArgsMatch(args, AnyArg(), HavePrefix("4"))).To(BeFalse())
+	})
+
+	It("should let positions without a matcher match anything", func() {
+		Expect(ArgsMatch(args)).To(BeTrue())
+	})
+
+	It("should not match more matchers than arguments", func() {
+		Expect(ArgsMatch(ArgList{"ann"}, "ann", AnyArg())).To(BeFalse())
+	})
+
+	It("should describe the matchers", func() {
+		Expect(AnyArg().String()).To(Equal("AnyArg()"))
+		Expect(Eq("ann").String()).To(Equal(`"ann"`))
+	})
+})
@@ -0,0 +1,68 @@
+package gospy_test
+
+import (
+	. "github.com/cfmobile/gospy"
+	"testing"
+)
+
+func benchmarkLookup(key string) (string, error) {
+	return key, nil
+}
+
+func benchmarkFormat(name string, count int, verbose bool) (string, error) {
+	return name, nil
+}
+
+func BenchmarkUnspied(b *testing.B) {
+	lookup := benchmarkLookup
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		lookup("key")
+	}
+}
+
+func BenchmarkSpy(b *testing.B) {
+	lookup := benchmarkLookup
+	spy := Spy(&lookup)
+	defer spy.Restore()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		lookup("key")
+	}
+}
+
+func BenchmarkSpyWithReflection(b *testing.B) {
+	format := benchmarkFormat
+	spy := Spy(&format)
+	defer spy.Restore()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		format("key", 1, true)
+	}
+}
+
+func BenchmarkSpyAndFakeWithReturn(b *testing.B) {
+	lookup := benchmarkLookup
+	spy := SpyAndFakeWithReturn(&lookup, "fake", nil)
+	defer spy.Restore()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		lookup("key")
+	}
+}
+
+func BenchmarkSpyCountOnly(b *testing.B) {
+	lookup := benchmarkLookup
+	spy := Spy(&lookup)
+	spy.SetRecording(CountOnly())
+	defer spy.Restore()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		lookup("key")
+	}
+}
@@ -0,0 +1,155 @@
+package gospy
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// An invocation of a callback argument made by the fake, see InvokeArg()
+type CallbackCall struct {
+	ArgIndex   int
+	Args       []interface{}
+	Returns    []interface{}
+	PanicValue interface{}
+	Panicked   bool
+}
+
+type argInvocation struct {
+	argIndex int
+	args     []reflect.Value
+	async    bool
+}
+
+// Makes every subsequent call invoke the callback at argIndex with callbackArgs,
+// before answering. Invocations run in the order they were added
+func (self *GoSpy) InvokeArg(argIndex int, callbackArgs ...interface{}) *GoSpy {
+	return self.addInvocation(argIndex, callbackArgs, false)
+}
+
+// As InvokeArg(), from another goroutine. The asynchronous invocations of a call
+// run one after the other, in the order they were added
+func (self *GoSpy) InvokeArgAsync(argIndex int, callbackArgs ...interface{}) *GoSpy {
+	return self.addInvocation(argIndex, callbackArgs, true)
+}
+
+func (self *GoSpy) CallbacksForCall(callIndex uint) []CallbackCall {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	position := self.mustPositionOf(int(callIndex))
+
+	return append([]CallbackCall(nil), self.records[position].callbacks...)
+}
+
+func (self *GoSpy) addInvocation(argIndex int, callbackArgs []interface{}, async bool) *GoSpy {
+	targetType := self.mock.target.Type()
+
+	args, err := callbackArgsAreValid(targetType, argIndex, callbackArgs)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.invocations = append(self.invocations, argInvocation{argIndex: argIndex, args: args, async: async})
+	return self
+}
+
+// Wraps behaviour so the invocations are made first, keeping what the callbacks return in record
+func (self *GoSpy) withInvocations(record *callRecord, invocations []argInvocation, behaviour func([]reflect.Value) []reflect.Value) func([]reflect.Value) []reflect.Value {
+	return func(args []reflect.Value) []reflect.Value {
+		var async []argInvocation
+		for _, invocation := range invocations {
+			if invocation.async {
+				async = append(async, invocation)
+			} else {
+				self.invoke(record, invocation, args, false)
+			}
+		}
+
+		if len(async) > 0 {
+			go func() {
+				for _, invocation := range async {
+					self.invoke(record, invocation, args, true)
+				}
+			}()
+		}
+
+		return behaviour(args)
+	}
+}
+
+// Panics of asynchronous callbacks are recovered and kept, as there is no caller to raise them to
+func (self *GoSpy) invoke(record *callRecord, invocation argInvocation, args []reflect.Value, async bool) {
+	callback := args[invocation.argIndex]
+	if callback.IsNil() {
+		return
+	}
+
+	callbackCall := CallbackCall{ArgIndex: invocation.argIndex, Args: valuesToInterfaces(invocation.args)}
+	defer func() {
+		if async {
+			callbackCall.PanicValue = recover()
+			callbackCall.Panicked = callbackCall.PanicValue != nil
+		} else if r := recover(); r != nil {
+			callbackCall.PanicValue, callbackCall.Panicked = r, true
+			self.addCallbackCall(record, callbackCall)
+			panic(r)
+		}
+
+		self.addCallbackCall(record, callbackCall)
+	}()
+
+	callbackCall.Returns = valuesToInterfaces(callback.Call(invocation.args))
+}
+
+func (self *GoSpy) addCallbackCall(record *callRecord, callbackCall CallbackCall) {
+	if record == nil {
+		return
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	if record.recorded {
+		record.callbacks = append(record.callbacks, callbackCall)
+	}
+}
+
+func callbackArgsAreValid(targetType reflect.Type, argIndex int, callbackArgs []interface{}) ([]reflect.Value, error) {
+	if argIndex < 0 || argIndex >= targetType.NumIn() {
+		return nil, errors.New(fmt.Sprintf("Target has no argument %d to invoke [target: %+v]", argIndex, targetType))
+	}
+
+	callbackType := targetType.In(argIndex)
+	if callbackType.Kind() != reflect.Func {
+		return nil, errors.New(fmt.Sprintf("Argument %d of the target is not a function [target: %+v, argument: %+v]", argIndex, targetType, callbackType))
+	}
+
+	if callbackType.IsVariadic() {
+		return nil, errors.New(fmt.Sprintf("Variadic callbacks can't be invoked [callback: %+v]", callbackType))
+	}
+
+	if len(callbackArgs) != callbackType.NumIn() {
+		return nil, errors.New(fmt.Sprintf("Invalid number of callback arguments [callback: %+v, arguments: %d]", callbackType, len(callbackArgs)))
+	}
+
+	args := make([]reflect.Value, len(callbackArgs))
+	for i, callbackArg := range callbackArgs {
+		args[i] = reflect.New(callbackType.In(i)).Elem()
+
+		if callbackArg == nil {
+			continue
+		}
+
+		value := reflect.ValueOf(callbackArg)
+		if !value.Type().AssignableTo(callbackType.In(i)) {
+			return nil, errors.New(fmt.Sprintf("Callback argument %d has the wrong type [callback: %+v, argument: %+v]", i, callbackType, value.Type()))
+		}
+		args[i].Set(value)
+	}
+
+	return args, nil
+}
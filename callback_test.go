@@ -0,0 +1,126 @@
+package gospy_test
+
+import (
+	"errors"
+	. "github.com/cfmobile/gospy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Invoking callback arguments", func() {
+	var walk func(string, func(string) error) error
+	var subscribe func(string, func(string))
+	var visited []string
+
+	BeforeEach(func() {
+		visited = nil
+		walk = func(string, func(string) error) error {
+			return errors.New("walked the real tree")
+		}
+		subscribe = func(string, func(string)) {}
+	})
+
+	visit := func(path string) error {
+		visited = append(visited, path)
+		if path == "bad.txt" {
+			return errors.New("can't visit")
+		}
+		return nil
+	}
+
+	It("should invoke the callback with the values, in order, before answering", func() {
+		spy := SpyAndFake(&walk).InvokeArg(1, "a.txt").InvokeArg(1, "bad.txt")
+		defer spy.Restore()
+
+		Expect(walk("/", visit)).To(BeNil())
+		Expect(visited).To(Equal([]string{"a.txt", "bad.txt"}))
+	})
+
+	It("should record what the callback returned", func() {
+		spy := SpyAndFake(&walk).InvokeArg(1, "a.txt").InvokeArg(1, "bad.txt")
+		defer spy.Restore()
+
+		walk("/", visit)
+
+		callbacks := spy.CallbacksForCall(0)
+		Expect(callbacks).To(HaveLen(2))
+		Expect(callbacks[0]).To(Equal(CallbackCall{ArgIndex: 1, Args: []interface{}{"a.txt"}, Returns: []interface{}{nil}}))
+		Expect(callbacks[1].Returns[0]).To(MatchError("can't visit"))
+		Expect(spy.Query().First().Callbacks).To(Equal(callbacks))
+	})
+
+	It("should skip nil callbacks", func() {
+		spy := SpyAndFake(&walk).InvokeArg(1, "a.txt")
+		defer spy.Restore()
+
+		Expect(walk("/", nil)).To(BeNil())
+		Expect(spy.CallbacksForCall(0)).To(BeEmpty())
+	})
+
+	It("should record and raise the panics of the callback", func() {
+		spy := SpyAndFake(&walk).InvokeArg(1, "a.txt")
+		defer spy.Restore()
+
+		Expect(func() {
+			walk("/", func(string) error { panic("boom") })
+		}).To(Panic())
+
+		Expect(spy.CallbacksForCall(0)[0].Panicked).To(BeTrue())
+		Expect(spy.String()).To(ContainSubstring(`panicked: "boom"`))
+	})
+
+	It("should invoke callbacks asynchronously, one after the other", func() {
+		spy := SpyAndFake(&subscribe).InvokeArgAsync(1, "first").InvokeArgAsync(1, "second")
+		defer spy.Restore()
+		received := make(chan string, 2)
+
+		subscribe("news", func(message string) {
+			received <- message
+		})
+
+		Eventually(received).Should(Receive(Equal("first")))
+		Eventually(received).Should(Receive(Equal("second")))
+		Eventually(func() []CallbackCall {
+			return spy.CallbacksForCall(0)
+		}).Should(HaveLen(2))
+	})
+
+	It("should keep the panics of asynchronous callbacks", func() {
+		spy := SpyAndFake(&subscribe).InvokeArgAsync(1, "first")
+		defer spy.Restore()
+
+		subscribe("news", func(string) { panic("boom") })
+
+		Eventually(func() []CallbackCall {
+			return spy.CallbacksForCall(0)
+		}).Should(ConsistOf(CallbackCall{ArgIndex: 1, Args: []interface{}{"first"}, PanicValue: "boom", Panicked: true}))
+	})
+
+	It("should not invoke callbacks while passing calls through", func() {
+		spy := SpyAndFake(&walk).InvokeArg(1, "a.txt")
+		defer spy.Restore()
+
+		spy.PauseAndPassThrough()
+		Expect(walk("/", visit)).To(MatchError("walked the real tree"))
+		Expect(visited).To(BeEmpty())
+	})
+
+	Context("when set up with an invalid callback", func() {
+		It("should refuse arguments that are not functions", func() {
+			spy := SpyAndFake(&walk)
+			defer spy.Restore()
+
+			Expect(func() { spy.InvokeArg(0, "a.txt") }).To(Panic())
+			Expect(func() { spy.InvokeArg(2, "a.txt") }).To(Panic())
+		})
+
+		It("should refuse values that don't fit the callback", func() {
+			spy := SpyAndFake(&walk)
+			defer spy.Restore()
+
+			Expect(func() { spy.InvokeArg(1) }).To(Panic())
+			Expect(func() { spy.InvokeArg(1, 42) }).To(Panic())
+			Expect(func() { spy.InvokeArg(1, "a.txt", "b.txt") }).To(Panic())
+		})
+	})
+})
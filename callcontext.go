@@ -0,0 +1,116 @@
+package gospy
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// What a fake set with SpyAndFakeWithCallFunc() knows about the call it answers
+type CallContext struct {
+	Index int // -1 for calls made while the spy is paused
+	Seq   uint64
+	Spy   *GoSpy
+	State *CallState
+	args  []reflect.Value
+}
+
+// Values kept by a spy across calls, for fakes that need state
+type CallState struct {
+	mutex  sync.Mutex
+	values map[string]interface{}
+}
+
+func SpyAndFakeWithCallFunc(targetFuncPtr interface{}, callFunc func(c *CallContext, args ...interface{}) []interface{}) *GoSpy {
+	spy := createSpy(targetFuncPtr)
+
+	if callFunc == nil {
+		panic("Fake function can't be nil")
+	}
+
+	spy.setBehaviourFor(spy.getFnWithCallFunc(callFunc), nil)
+	spy.install(spy.newWrapper())
+	return spy
+}
+
+// The state shared by the calls to the spy
+func (self *GoSpy) State() *CallState {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	if self.state == nil {
+		self.state = &CallState{values: make(map[string]interface{})}
+	}
+
+	return self.state
+}
+
+// Calls the function the spy delegates to, the original or the next spy outward,
+// with args or, if there are none, the arguments of the call
+func (self *CallContext) CallOriginal(args ...interface{}) []interface{} {
+	callArgs := self.args
+	if len(args) > 0 {
+		targetType := self.Spy.mock.target.Type()
+
+		var err error
+		if callArgs, err = valuesOfTypes(args, argTypeOf(targetType), targetType.NumIn()); err != nil {
+			panic(fmt.Sprintf("Invalid arguments to call the original %+v with: %s", targetType, err.Error()))
+		}
+	}
+
+	return valuesToInterfaces(callWithArgs(self.Spy.outer(), callArgs))
+}
+
+func (self *CallState) Get(key string) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	return self.values[key]
+}
+
+func (self *CallState) Set(key string, value interface{}) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.values[key] = value
+}
+
+// Replaces the value of key with what update makes of it, atomically, and returns it
+func (self *CallState) Update(key string, update func(value interface{}) interface{}) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.values[key] = update(self.values[key])
+	return self.values[key]
+}
+
+func (self *GoSpy) getFnWithCallFunc(callFunc func(c *CallContext, args ...interface{}) []interface{}) func(record *callRecord) func(args []reflect.Value) []reflect.Value {
+	targetType := self.mock.target.Type()
+
+	return func(record *callRecord) func(args []reflect.Value) []reflect.Value {
+		return func(args []reflect.Value) []reflect.Value {
+			context := &CallContext{Index: -1, Spy: self, State: self.State(), args: args}
+			if record != nil {
+				context.Index, context.Seq = record.index, record.seq
+			}
+
+			results, err := valuesOfTypes(callFunc(context, valuesToInterfaces(args)...), targetType.Out, targetType.NumOut())
+			if err != nil {
+				panic(fmt.Sprintf("Fake function returned invalid values for %+v: %s", targetType, err.Error()))
+			}
+
+			return results
+		}
+	}
+}
+
+// Variadic arguments are passed as a slice, as they are recorded
+func argTypeOf(targetType reflect.Type) func(int) reflect.Type {
+	return func(i int) reflect.Type {
+		if targetType.IsVariadic() && i == targetType.NumIn()-1 {
+			return reflect.SliceOf(targetType.In(i).Elem())
+		}
+
+		return targetType.In(i)
+	}
+}
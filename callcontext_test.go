@@ -0,0 +1,139 @@
+package gospy_test
+
+import (
+	"errors"
+	. "github.com/cfmobile/gospy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"strings"
+)
+
+var _ = Describe("Fakes receiving call metadata", func() {
+	var fetch func(string) (string, error)
+	var join func(string, ...string) string
+
+	BeforeEach(func() {
+		fetch = func(url string) (string, error) {
+			return "content of " + url, nil
+		}
+		join = func(sep string, parts ...string) string {
+			return strings.Join(parts, sep)
+		}
+	})
+
+	It("should pass the arguments and the index of the call", func() {
+		spy := SpyAndFakeWithCallFunc(&fetch, func(c *CallContext, args ...interface{}) []interface{} {
+			if c.Index == 0 {
+				return []interface{}{"", errors.New("first call fails")}
+			}
+			return []interface{}{"fake " + args[0].(string), nil}
+		})
+		defer spy.Restore()
+
+		_, err := fetch("a")
+		Expect(err).To(MatchError("first call fails"))
+		Expect(fetch("b")).To(Equal("fake b"))
+	})
+
+	It("should expose the spy and the sequence number of the call", func() {
+		var spies []*GoSpy
+		var seqs []uint64
+		spy := SpyAndFakeWithCallFunc(&fetch, func(c *CallContext, args ...interface{}) []interface{} {
+			spies = append(spies, c.Spy)
+			seqs = append(seqs, c.Seq)
+			return []interface{}{"", nil}
+		})
+		defer spy.Restore()
+
+		fetch("a")
+
+		Expect(spies).To(Equal([]*GoSpy{spy}))
+		Expect(seqs).To(Equal([]uint64{spy.Query().First().Seq}))
+	})
+
+	It("should call the original with the arguments of the call, or others", func() {
+		spy := SpyAndFakeWithCallFunc(&fetch, func(c *CallContext, args ...interface{}) []interface{} {
+			if args[0] == "cached" {
+				return c.CallOriginal("cache")
+			}
+			return c.CallOriginal()
+		})
+		defer spy.Restore()
+
+		Expect(fetch("a")).To(Equal("content of a"))
+		Expect(fetch("cached")).To(Equal("content of cache"))
+	})
+
+	It("should refuse to call the original with arguments that don't fit", func() {
+		spy := SpyAndFakeWithCallFunc(&fetch, func(c *CallContext, args ...interface{}) []interface{} {
+			return c.CallOriginal(42)
+		})
+		defer spy.Restore()
+
+		Expect(func() { fetch("a") }).To(Panic())
+	})
+
+	It("should pass variadic arguments as a slice", func() {
+		spy := SpyAndFakeWithCallFunc(&join, func(c *CallContext, args ...interface{}) []interface{} {
+			if len(args[1].([]string)) == 0 {
+				return []interface{}{"nothing to join"}
+			}
+			return c.CallOriginal()
+		})
+		defer spy.Restore()
+
+		Expect(join("-", "a", "b")).To(Equal("a-b"))
+		Expect(join("-")).To(Equal("nothing to join"))
+	})
+
+	It("should keep state across calls", func() {
+		spy := SpyAndFakeWithCallFunc(&fetch, func(c *CallContext, args ...interface{}) []interface{} {
+			if c.State.Get(args[0].(string)) != nil {
+				return []interface{}{"", errors.New("already fetched")}
+			}
+			c.State.Set(args[0].(string), true)
+			c.State.Update("fetched", func(value interface{}) interface{} {
+				count, _ := value.(int)
+				return count + 1
+			})
+			return []interface{}{"fetched", nil}
+		})
+		defer spy.Restore()
+
+		fetch("a")
+		fetch("b")
+		_, err := fetch("a")
+
+		Expect(err).To(MatchError("already fetched"))
+		Expect(spy.State().Get("fetched")).To(Equal(2))
+	})
+
+	It("should give calls made while paused no index", func() {
+		var indices []int
+		spy := SpyAndFakeWithCallFunc(&fetch, func(c *CallContext, args ...interface{}) []interface{} {
+			indices = append(indices, c.Index)
+			return []interface{}{"", nil}
+		})
+		defer spy.Restore()
+
+		spy.Ignoring(func() {
+			fetch("a")
+		})
+		fetch("b")
+
+		Expect(indices).To(Equal([]int{-1, 0}))
+	})
+
+	It("should refuse return values that don't fit the target", func() {
+		spy := SpyAndFakeWithCallFunc(&fetch, func(c *CallContext, args ...interface{}) []interface{} {
+			return []interface{}{42}
+		})
+		defer spy.Restore()
+
+		Expect(func() { fetch("a") }).To(Panic())
+	})
+
+	It("should refuse a nil fake function", func() {
+		Expect(func() { SpyAndFakeWithCallFunc(&fetch, nil) }).To(Panic())
+	})
+})
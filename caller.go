@@ -0,0 +1,166 @@
+package gospy
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// Where a recorded call came from, kept when recording with CaptureCallers() or CaptureStacks()
+type CallOrigin struct {
+	Caller    CallSite
+	Stack     []CallSite
+	Goroutine int64
+}
+
+type CallSite struct {
+	Function string
+	File     string
+	Line     int
+}
+
+func (self CallSite) String() string {
+	return fmt.Sprintf("%s:%d", filepath.Base(self.File), self.Line)
+}
+
+var gospyPackage = reflect.TypeOf(GoSpy{}).PkgPath()
+
+const maxCapturedFrames = 64
+
+// Keeps the file:line and goroutine of each call
+func CaptureCallers() RecordingOption {
+	return func(config *recordingConfig) {
+		config.captureCallers = true
+	}
+}
+
+// Keeps the file:line and goroutine of each call, and up to depth frames of its stack
+func CaptureStacks(depth int) RecordingOption {
+	return func(config *recordingConfig) {
+		config.captureCallers = true
+		config.stackDepth = depth
+	}
+}
+
+func (self *GoSpy) OriginForCall(callIndex uint) CallOrigin {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	position := self.mustPositionOf(int(callIndex))
+
+	origin := self.records[position].origin
+	if origin == nil {
+		panic(fmt.Sprintf("The origin of call %d was not captured. Record with CaptureCallers() or CaptureStacks() to keep it", callIndex))
+	}
+
+	return *origin
+}
+
+// Recorded calls made from a function whose qualified name, such as
+// "github.com/org/pkg.(*Type).Method", matches pattern. The whole captured
+// stack is searched when recording with CaptureStacks()
+func (self *GoSpy) CallsFrom(pattern string) CallList {
+	matcher, err := regexp.Compile(pattern)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid caller pattern %q: %s", pattern, err.Error()))
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	if err := self.originsAreCaptured(); err != nil {
+		panic(err.Error())
+	}
+
+	var calls CallList
+	for i, record := range self.records {
+		if record.origin.matches(matcher) {
+			calls = append(calls, self.calls[i])
+		}
+	}
+
+	return calls
+}
+
+func (self *GoSpy) originsAreCaptured() error {
+	if !self.recording.captureCallers {
+		return errors.New("Spy is not capturing callers. Record with CaptureCallers() or CaptureStacks() to query calls by caller")
+	}
+
+	return nil
+}
+
+func (self *CallOrigin) matches(matcher *regexp.Regexp) bool {
+	if self == nil {
+		return false // Recorded before callers were captured
+	}
+
+	if matcher.MatchString(self.Caller.Function) {
+		return true
+	}
+
+	for _, site := range self.Stack {
+		if matcher.MatchString(site.Function) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Captures the origin of the call being made, skipping the frames of gospy,
+// reflect and the runtime so that the caller is the code calling the target
+func captureOrigin(stackDepth int) *CallOrigin {
+	pcs := make([]uintptr, maxCapturedFrames)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+
+	origin := &CallOrigin{Goroutine: goroutineID()}
+	found := false
+
+	for {
+		frame, more := frames.Next()
+
+		if !isInternalFrame(frame.Function) {
+			site := CallSite{Function: frame.Function, File: frame.File, Line: frame.Line}
+			if !found {
+				origin.Caller, found = site, true
+			}
+			if len(origin.Stack) < stackDepth {
+				origin.Stack = append(origin.Stack, site)
+			}
+		}
+
+		if !more || (found && len(origin.Stack) >= stackDepth) {
+			return origin
+		}
+	}
+}
+
+// Whether a frame of the current stack outside gospy matches
+func stackMatches(matcher *regexp.Regexp) bool {
+	pcs := make([]uintptr, maxCapturedFrames)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+
+	for {
+		frame, more := frames.Next()
+
+		if !isInternalFrame(frame.Function) && matcher.MatchString(frame.Function) {
+			return true
+		}
+
+		if !more {
+			return false
+		}
+	}
+}
+
+func isInternalFrame(function string) bool {
+	return strings.HasPrefix(function, gospyPackage+".") ||
+		strings.HasPrefix(function, gospyPackage+"/") ||
+		strings.HasPrefix(function, "reflect.") ||
+		strings.HasPrefix(function, "runtime.")
+}
@@ -0,0 +1,198 @@
+package gospy_test
+
+import (
+	"errors"
+	. "github.com/cfmobile/gospy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"runtime"
+	"strings"
+)
+
+func callerHelperNotify(notify func(string) error, message string) {
+	notify(message)
+}
+
+func callerHelperRelay(notify func(string) error, message string) {
+	callerHelperNotify(notify, message)
+}
+
+func callerLine() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line
+}
+
+var _ = Describe("Caller capture", func() {
+	var subject *GoSpy
+	var notify func(string) error
+	var panicked bool
+
+	BeforeEach(func() {
+		panicked = false
+		notify = func(string) error {
+			return nil
+		}
+		subject = Spy(&notify)
+	})
+
+	AfterEach(func() {
+		subject.Restore()
+	})
+
+	Context("when capturing callers", func() {
+		BeforeEach(func() {
+			subject.SetRecording(CaptureCallers())
+		})
+
+		It("should keep the file:line and goroutine of each call", func() {
+			line := callerLine() + 1
+			notify("a")
+
+			origin := subject.OriginForCall(0)
+			Expect(origin.Caller.File).To(HaveSuffix("caller_test.go"))
+			Expect(origin.Caller.Line).To(Equal(line))
+			Expect(origin.Caller.Function).To(ContainSubstring("gospy_test"))
+			Expect(origin.Goroutine).NotTo(BeZero())
+			Expect(origin.Stack).To(BeEmpty())
+		})
+
+		It("should show the caller in the call log", func() {
+			line := callerLine() + 1
+			notify("a")
+
+			Expect(subject.String()).To(ContainSubstring(`#0 ("a") => (nil) from caller_test.go:%d`, line))
+		})
+
+		It("should find the calls made from a function", func() {
+			notify("direct")
+			callerHelperNotify(notify, "helper")
+
+			Expect(subject.CallsFrom(`\.callerHelperNotify$`)).To(Equal(CallList{{"helper"}}))
+			Expect(subject.CallsFrom(`^github\.com/cfmobile/gospy_test\.`)).To(Equal(CallList{{"direct"}, {"helper"}}))
+			Expect(subject.CallsFrom(`\.callerHelperRelay$`)).To(BeEmpty())
+		})
+
+		It("should record the goroutine that made the call", func() {
+			done := make(chan bool)
+			go func() {
+				defer close(done)
+				notify("from goroutine")
+			}()
+			<-done
+			notify("from test")
+
+			Expect(subject.OriginForCall(0).Goroutine).NotTo(Equal(subject.OriginForCall(1).Goroutine))
+		})
+	})
+
+	Context("when capturing stacks", func() {
+		BeforeEach(func() {
+			subject.SetRecording(CaptureStacks(3))
+		})
+
+		It("should keep the trimmed stack, starting at the caller", func() {
+			callerHelperNotify(notify, "helper")
+
+			stack := subject.OriginForCall(0).Stack
+			Expect(len(stack)).To(BeNumerically(">", 1))
+			Expect(len(stack)).To(BeNumerically("<=", 3))
+			Expect(stack[0]).To(Equal(subject.OriginForCall(0).Caller))
+			Expect(stack[0].Function).To(HaveSuffix(".callerHelperNotify"))
+			for _, site := range stack {
+				Expect(strings.HasPrefix(site.Function, "github.com/cfmobile/gospy.")).To(BeFalse())
+			}
+		})
+
+		It("should search the whole stack for the callers", func() {
+			notify("direct")
+			callerHelperRelay(notify, "relayed")
+
+			Expect(subject.CallsFrom(`\.callerHelperRelay$`)).To(Equal(CallList{{"relayed"}}))
+		})
+	})
+
+	Context("when callers are not captured", func() {
+		It("should refuse to query calls by caller", func() {
+			notify("a")
+
+			func() {
+				defer func() {
+					panicked = recover() != nil
+				}()
+				subject.CallsFrom("gospy_test")
+			}()
+
+			Expect(panicked).To(BeTrue())
+		})
+
+		It("should not show callers in the call log", func() {
+			notify("a")
+
+			Expect(subject.String()).NotTo(ContainSubstring(" from "))
+		})
+	})
+})
+
+var _ = Describe("Recording only calls from some callers", func() {
+	var notify func(string) error
+	var delivered []string
+
+	BeforeEach(func() {
+		delivered = nil
+		notify = func(message string) error {
+			delivered = append(delivered, message)
+			return nil
+		}
+	})
+
+	It("should only count and record the calls with a matching frame in their stack", func() {
+		spy := Spy(&notify)
+		defer spy.Restore()
+		spy.SetRecording(OnlyFrom(`\.callerHelperRelay$`))
+
+		notify("setup")
+		callerHelperRelay(notify, "relayed")
+		callerHelperNotify(notify, "helper")
+
+		Expect(spy.CallCount()).To(Equal(1))
+		Expect(spy.Calls()).To(Equal(CallList{{"relayed"}}))
+		Expect(delivered).To(Equal([]string{"setup", "relayed", "helper"}))
+	})
+
+	It("should only fake the matching calls, passing the others through to the original", func() {
+		spy := SpyAndFakeWithReturn(&notify, errors.New("fake"))
+		defer spy.Restore()
+		spy.SetRecording(OnlyFrom(`\.callerHelperRelay$`))
+
+		Expect(notify("setup")).To(BeNil())
+		callerHelperRelay(notify, "relayed")
+
+		Expect(delivered).To(Equal([]string{"setup"}))
+		Expect(spy.Calls()).To(Equal(CallList{{"relayed"}}))
+	})
+
+	It("should match calls by package", func() {
+		spy := Spy(&notify)
+		defer spy.Restore()
+		spy.SetRecording(OnlyFromPackage("github.com/cfmobile/gospy_test"))
+
+		notify("test")
+
+		Expect(spy.CallCount()).To(Equal(1))
+	})
+
+	It("should not match the frames of other packages", func() {
+		spy := Spy(&notify)
+		defer spy.Restore()
+		spy.SetRecording(OnlyFromPackage("github.com/cfmobile/other"))
+
+		notify("test")
+
+		Expect(spy.CallCount()).To(BeZero())
+		Expect(delivered).To(Equal([]string{"test"}))
+	})
+
+	It("should refuse invalid patterns", func() {
+		Expect(func() { OnlyFrom("(") }).To(Panic())
+	})
+})
@@ -0,0 +1,145 @@
+package gospy
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Matches arguments of type T, capturing them from every call that matches as a whole
+type ArgCaptor[T any] struct {
+	mutex    sync.Mutex
+	values   []T
+	captured chan struct{}
+}
+
+// Implemented by matchers that keep the arguments of the calls they match
+type capturingMatcher interface {
+	capture(arg interface{})
+}
+
+func Captor[T any]() *ArgCaptor[T] {
+	return &ArgCaptor[T]{captured: make(chan struct{})}
+}
+
+// Matches the recorded calls of the spy against matchers, then every subsequent
+// call as it is made, so the captors among them capture their arguments
+func (self *GoSpy) CaptureWith(matchers ...interface{}) {
+	// Listening from the same hold of the lock as the copy, so no call falls in between
+	self.mutex.Lock()
+	recorded := append(CallList(nil), self.calls...)
+	self.listeners = append(self.listeners, func(args ArgList) {
+		captureIfMatching(args, matchers)
+	})
+	self.mutex.Unlock()
+
+	for _, args := range recorded {
+		captureIfMatching(args, matchers)
+	}
+}
+
+// The last value captured
+func (self *ArgCaptor[T]) Value() T {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	if len(self.values) == 0 {
+		panic(fmt.Sprintf("No %s argument was captured", self.argType()))
+	}
+
+	return self.values[len(self.values)-1]
+}
+
+func (self *ArgCaptor[T]) All() []T {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	return append([]T(nil), self.values...)
+}
+
+// Waits up to timeout for a value to be captured, returning the last one
+func (self *ArgCaptor[T]) Wait(timeout time.Duration) (T, bool) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		self.mutex.Lock()
+		captured := self.captured
+		if len(self.values) > 0 {
+			value := self.values[len(self.values)-1]
+			self.mutex.Unlock()
+			return value, true
+		}
+		self.mutex.Unlock()
+
+		select {
+		case <-captured:
+		case <-timer.C:
+			var zero T
+			return zero, false
+		}
+	}
+}
+
+func (self *ArgCaptor[T]) MatchesArg(arg interface{}) bool {
+	_, ok := self.convert(arg)
+	return ok
+}
+
+func (self *ArgCaptor[T]) String() string {
+	return fmt.Sprintf("Captor[%s]()", self.argType())
+}
+
+func (self *ArgCaptor[T]) capture(arg interface{}) {
+	value, ok := self.convert(arg)
+	if !ok {
+		return
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.values = append(self.values, value)
+	close(self.captured)
+	self.captured = make(chan struct{})
+}
+
+func captureIfMatching(args ArgList, matchers []interface{}) {
+	if ArgsMatch(args, matchers...) {
+		captureArgs(args, matchers)
+	}
+}
+
+// Has the captors among matchers capture the arguments of a call they matched
+func captureArgs(args ArgList, matchers []interface{}) {
+	for i, matcher := range matchers {
+		if captor, ok := matcher.(capturingMatcher); ok {
+			captor.capture(args[i])
+		}
+	}
+}
+
+// Nil arguments are captured as the zero value of types that can be nil
+func (self *ArgCaptor[T]) convert(arg interface{}) (T, bool) {
+	if arg == nil {
+		var zero T
+		return zero, canBeNil(self.argType())
+	}
+
+	value, ok := arg.(T)
+	return value, ok
+}
+
+func (self *ArgCaptor[T]) argType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func canBeNil(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+		return true
+	default:
+		return false
+	}
+}
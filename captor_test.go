@@ -0,0 +1,160 @@
+package gospy_test
+
+import (
+	. "github.com/cfmobile/gospy"
+	"github.com/cfmobile/gospy/ginkgo_ext/matchers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"sync"
+	"time"
+)
+
+type captorRequest struct {
+	ID string
+}
+
+var _ = Describe("Argument captors", func() {
+	var subject *GoSpy
+	var subscribe func(string, func(string))
+	var submit func(*captorRequest) error
+
+	BeforeEach(func() {
+		subscribe = func(string, func(string)) {}
+		submit = func(*captorRequest) error {
+			return nil
+		}
+		subject = SpyAndFake(&subscribe)
+	})
+
+	AfterEach(func() {
+		subject.Restore()
+	})
+
+	It("should capture the arguments of the calls matching as a whole", func() {
+		topic := Captor[string]()
+
+		subscribe("news", func(string) {})
+		subscribe("sport", func(string) {})
+		subject.CaptureWith(topic, AnyArg())
+
+		Expect(topic.All()).To(Equal([]string{"news", "sport"}))
+		Expect(topic.Value()).To(Equal("sport"))
+	})
+
+	It("should not capture from calls that don't match", func() {
+		handler := Captor[func(string)]()
+
+		subscribe("news", func(string) {})
+		subject.CaptureWith("sport", handler)
+
+		Expect(handler.All()).To(BeEmpty())
+	})
+
+	It("should only match when querying, without capturing", func() {
+		topic := Captor[string]()
+
+		subscribe("news", nil)
+		query := subject.Query()
+
+		Expect(query.Count(topic)).To(Equal(1))
+		Expect(query.Filter(topic)).To(HaveLen(1))
+		Expect(topic.All()).To(BeEmpty())
+	})
+
+	It("should capture callbacks so they can be invoked", func() {
+		var received []string
+		handler := Captor[func(string)]()
+
+		subscribe("news", func(message string) {
+			received = append(received, message)
+		})
+		subject.CaptureWith("news", handler)
+
+		handler.Value()("hello")
+		Expect(received).To(Equal([]string{"hello"}))
+	})
+
+	It("should not match arguments of other types", func() {
+		subscribe("news", nil)
+
+		Expect(subject.Query().Count(Captor[int]())).To(BeZero())
+	})
+
+	It("should capture nil arguments of types that can be nil", func() {
+		spy := Spy(&submit)
+		defer spy.Restore()
+		request := Captor[*captorRequest]()
+
+		submit(nil)
+		submit(&captorRequest{ID: "42"})
+		spy.CaptureWith(request)
+
+		Expect(request.All()).To(Equal([]*captorRequest{nil, {ID: "42"}}))
+	})
+
+	It("should match without capturing when asserting with MatchArgs", func() {
+		topic := Captor[string]()
+
+		subscribe("news", nil)
+
+		Expect(subject.ArgsForCall(0)).To(matchers.MatchArgs(topic, AnyArg()))
+		Expect(topic.All()).To(BeEmpty())
+	})
+
+	It("should refuse to return a value when nothing was captured", func() {
+		Expect(func() { Captor[string]().Value() }).To(Panic())
+	})
+
+	Context("when capturing the calls as they are made", func() {
+		It("should capture the recorded and subsequent calls", func() {
+			topic := Captor[string]()
+
+			subscribe("news", nil)
+			subject.CaptureWith(topic)
+			subscribe("sport", nil)
+
+			Expect(topic.All()).To(Equal([]string{"news", "sport"}))
+		})
+
+		It("should capture every call made while it starts capturing", func() {
+			topic := Captor[string]()
+
+			var wait sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wait.Add(1)
+				go func() {
+					defer wait.Done()
+					for j := 0; j < 200; j++ {
+						subscribe("news", nil)
+					}
+				}()
+			}
+			subject.CaptureWith(topic)
+			wait.Wait()
+
+			Expect(topic.All()).To(HaveLen(subject.CallCount()))
+		})
+
+		It("should wait for a call to be captured", func() {
+			topic := Captor[string]()
+			subject.CaptureWith(topic)
+
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+				subscribe("news", nil)
+			}()
+
+			value, ok := topic.Wait(time.Second)
+			Expect(ok).To(BeTrue())
+			Expect(value).To(Equal("news"))
+		})
+
+		It("should give up waiting after the timeout", func() {
+			topic := Captor[string]()
+			subject.CaptureWith(topic)
+
+			_, ok := topic.Wait(10 * time.Millisecond)
+			Expect(ok).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,453 @@
+package gospy
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+)
+
+type CassetteFormat int
+
+const (
+	CassetteFromExtension CassetteFormat = iota // .gob files use gob, anything else JSON
+	CassetteJSON
+	CassetteGob
+)
+
+// Converts values of a type that the cassette format can't serialise on its own
+type CassetteEncoder interface {
+	Encode(value interface{}) ([]byte, error)
+	Decode(data []byte) (interface{}, error)
+}
+
+type CassetteOption func(*cassetteConfig)
+
+func WithCassetteFormat(format CassetteFormat) CassetteOption {
+	return func(config *cassetteConfig) {
+		config.format = format
+	}
+}
+
+func WithCassetteEncoder(valueType reflect.Type, encoder CassetteEncoder) CassetteOption {
+	return func(config *cassetteConfig) {
+		config.encoders[valueType] = encoder
+	}
+}
+
+func (self *GoSpy) Record(path string, options ...CassetteOption) error {
+	targetType := self.mock.target.Type()
+	recorder := &cassetteFile{
+		path:     path,
+		config:   newCassetteConfig(path, options),
+		cassette: cassette{Signature: targetType.String()},
+	}
+
+	if err := recorder.create(); err != nil {
+		return err
+	}
+
+	self.mutex.Lock()
+	behaviourFor := self.behaviourFor
+	self.mutex.Unlock()
+
+	self.setBehaviourFor(func(record *callRecord) func(args []reflect.Value) []reflect.Value {
+		behaviour := behaviourFor(record)
+
+		return func(args []reflect.Value) []reflect.Value {
+			results := behaviour(args)
+
+			if err := recorder.append(args, results); err != nil {
+				panic(fmt.Sprintf("Failed to record call to cassette %s: %s", path, err.Error()))
+			}
+
+			return results
+		}
+	}, nil) // Recording needs the reflected values
+
+	return nil
+}
+
+func SpyAndReplay(targetFuncPtr interface{}, path string, options ...CassetteOption) *GoSpy {
+	spy := createSpy(targetFuncPtr)
+
+	player := &cassetteFile{path: path, config: newCassetteConfig(path, options)}
+	if err := player.load(spy); err != nil {
+		panic(err.Error())
+	}
+
+	spy.setTargetFn(player.replay, nil)
+	return spy
+}
+
+type cassette struct {
+	Signature string
+	Calls     []cassetteCall
+}
+
+type cassetteCall struct {
+	Args    []cassetteValue
+	Returns []cassetteValue
+}
+
+// Raw holds the value in the cassette's own format, Encoded the output of a CassetteEncoder
+type cassetteValue struct {
+	Nil     bool            `json:",omitempty"`
+	Raw     json.RawMessage `json:",omitempty"`
+	Encoded []byte          `json:",omitempty"`
+}
+
+type cassetteConfig struct {
+	format   CassetteFormat
+	encoders map[reflect.Type]CassetteEncoder
+}
+
+// Behaviours run outside the spy's lock, so the mutex guards the file and the
+// replay state against concurrent calls
+type cassetteFile struct {
+	mutex    sync.Mutex
+	path     string
+	config   *cassetteConfig
+	cassette cassette
+
+	// Recording state: calls written so far, and where the end of a JSON cassette starts
+	recorded   int
+	jsonEnding int64
+
+	// Replay state, one entry per recorded call
+	args    [][]reflect.Value
+	returns []func([]reflect.Value) []reflect.Value
+	used    []bool
+}
+
+func newCassetteConfig(path string, options []CassetteOption) *cassetteConfig {
+	config := &cassetteConfig{
+		format:   CassetteFromExtension,
+		encoders: map[reflect.Type]CassetteEncoder{errorType: errorEncoder{}},
+	}
+
+	for _, option := range options {
+		option(config)
+	}
+
+	if config.format == CassetteFromExtension {
+		config.format = CassetteJSON
+		if filepath.Ext(path) == ".gob" {
+			config.format = CassetteGob
+		}
+	}
+
+	return config
+}
+
+func (self *cassetteFile) append(args []reflect.Value, results []reflect.Value) error {
+	var call cassetteCall
+	for _, arg := range args {
+		value, err := self.config.encode(arg)
+		if err != nil {
+			return err
+		}
+		call.Args = append(call.Args, value)
+	}
+
+	for _, result := range results {
+		value, err := self.config.encode(result)
+		if err != nil {
+			return err
+		}
+		call.Returns = append(call.Returns, value)
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	if self.config.format == CassetteGob {
+		return self.appendGob(call)
+	}
+	return self.appendJSON(call)
+}
+
+const jsonCassetteEnding = "\n  ]\n}\n"
+
+// Writes a cassette without calls. Calls are then added one at a time, so
+// the file is complete after every call without being rewritten
+func (self *cassetteFile) create() error {
+	if self.config.format == CassetteGob {
+		var buffer bytes.Buffer
+		if err := gob.NewEncoder(&buffer).Encode(self.cassette); err != nil {
+			return err
+		}
+		return os.WriteFile(self.path, buffer.Bytes(), 0644)
+	}
+
+	signature, err := json.Marshal(self.cassette.Signature)
+	if err != nil {
+		return err
+	}
+
+	beginning := "{\n  \"Signature\": " + string(signature) + ",\n  \"Calls\": ["
+	self.jsonEnding = int64(len(beginning))
+	return os.WriteFile(self.path, []byte(beginning+jsonCassetteEnding), 0644)
+}
+
+// Overwrites the end of the JSON document with the call, followed by the end again
+func (self *cassetteFile) appendJSON(call cassetteCall) error {
+	data, err := json.MarshalIndent(call, "    ", "  ")
+	if err != nil {
+		return err
+	}
+
+	separator := ",\n    "
+	if self.recorded == 0 {
+		separator = "\n    "
+	}
+	entry := separator + string(data)
+
+	file, err := os.OpenFile(self.path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.WriteAt([]byte(entry+jsonCassetteEnding), self.jsonEnding); err != nil {
+		file.Close()
+		return err
+	}
+
+	self.recorded++
+	self.jsonEnding += int64(len(entry))
+	return file.Close()
+}
+
+// Gob cassettes are a sequence of streams, the cassette without calls then one per call,
+// each with its own encoder so they can be appended without keeping the file open
+func (self *cassetteFile) appendGob(call cassetteCall) error {
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(call); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(self.path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(buffer.Bytes()); err != nil {
+		file.Close()
+		return err
+	}
+
+	self.recorded++
+	return file.Close()
+}
+
+func (self *cassetteFile) load(spy *GoSpy) error {
+	targetType := spy.mock.target.Type()
+	data, err := os.ReadFile(self.path)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Failed to read cassette %s: %s", self.path, err.Error()))
+	}
+
+	if self.config.format == CassetteGob {
+		err = decodeGobCassette(bytes.NewReader(data), &self.cassette)
+	} else {
+		err = json.Unmarshal(data, &self.cassette)
+	}
+
+	if err != nil {
+		return errors.New(fmt.Sprintf("Failed to decode cassette %s: %s", self.path, err.Error()))
+	}
+
+	if self.cassette.Signature != targetType.String() {
+		return errors.New(fmt.Sprintf("Cassette %s was recorded for a different signature [cassette: %s, target: %+v]", self.path, self.cassette.Signature, targetType))
+	}
+
+	for i, call := range self.cassette.Calls {
+		if self.config.format == CassetteJSON {
+			compactAll(call.Args)
+			compactAll(call.Returns)
+		}
+
+		args, err := self.decodeAll(call.Args, targetType.In)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Failed to decode the arguments of call %d in cassette %s: %s", i, self.path, err.Error()))
+		}
+
+		returns, err := self.decodeAll(call.Returns, targetType.Out)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Failed to decode the return values of call %d in cassette %s: %s", i, self.path, err.Error()))
+		}
+
+		var returnValues []interface{}
+		for _, value := range returns {
+			returnValues = append(returnValues, value.Interface())
+		}
+
+		self.args = append(self.args, args)
+		self.returns = append(self.returns, spy.getFnWithReturnValues(returnValues))
+	}
+
+	self.used = make([]bool, len(self.cassette.Calls))
+	return nil
+}
+
+// Decoders are given the bytes.Reader itself, which they don't read ahead of, so each stream can be read in turn
+func decodeGobCassette(reader *bytes.Reader, cassette *cassette) error {
+	if err := gob.NewDecoder(reader).Decode(cassette); err != nil {
+		return err
+	}
+
+	for {
+		var call cassetteCall
+		err := gob.NewDecoder(reader).Decode(&call)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		cassette.Calls = append(cassette.Calls, call)
+	}
+}
+
+func (self *cassetteFile) decodeAll(values []cassetteValue, typeOf func(int) reflect.Type) ([]reflect.Value, error) {
+	var decoded []reflect.Value
+	for i, value := range values {
+		result, err := self.config.decode(value, typeOf(i))
+		if err != nil {
+			return nil, err
+		}
+		decoded = append(decoded, result)
+	}
+
+	return decoded, nil
+}
+
+// Answers with the first unused recorded call matching the args, or the last used one if all were used
+func (self *cassetteFile) replay(args []reflect.Value) []reflect.Value {
+	self.mutex.Lock()
+	match := -1
+	for i := range self.args {
+		if self.argsMatch(self.cassette.Calls[i].Args, self.args[i], args) {
+			match = i
+			if !self.used[i] {
+				break
+			}
+		}
+	}
+
+	if match < 0 {
+		self.mutex.Unlock()
+		var call ArgList
+		for _, arg := range args {
+			call = append(call, arg.Interface())
+		}
+		panic(fmt.Sprintf("No call recorded in cassette %s matches the arguments %v", self.path, call))
+	}
+
+	self.used[match] = true
+	self.mutex.Unlock()
+
+	return self.returns[match](args)
+}
+
+// Encoded values are compared first, falling back to deep equality for formats that don't encode deterministically
+func (self *cassetteFile) argsMatch(recorded []cassetteValue, decoded []reflect.Value, args []reflect.Value) bool {
+	if len(recorded) != len(args) {
+		return false
+	}
+
+	for i, arg := range args {
+		encoded, err := self.config.encode(arg)
+		sameEncoding := err == nil && reflect.DeepEqual(encoded, recorded[i])
+
+		if !sameEncoding && !reflect.DeepEqual(decoded[i].Interface(), arg.Interface()) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (self *cassetteConfig) encode(value reflect.Value) (cassetteValue, error) {
+	if isNil(value) {
+		return cassetteValue{Nil: true}, nil
+	}
+
+	if encoder, ok := self.encoders[value.Type()]; ok {
+		data, err := encoder.Encode(value.Interface())
+		return cassetteValue{Encoded: data}, err
+	}
+
+	if self.format == CassetteGob {
+		// Wrapped in a struct, as gob can't encode interface values at the top level
+		holder := reflect.New(reflect.StructOf([]reflect.StructField{{Name: "Value", Type: value.Type()}}))
+		holder.Elem().Field(0).Set(value)
+
+		var buffer bytes.Buffer
+		err := gob.NewEncoder(&buffer).EncodeValue(holder)
+		return cassetteValue{Raw: buffer.Bytes()}, err
+	}
+
+	data, err := json.Marshal(value.Interface())
+	return cassetteValue{Raw: data}, err
+}
+
+func (self *cassetteConfig) decode(value cassetteValue, valueType reflect.Type) (reflect.Value, error) {
+	result := reflect.New(valueType).Elem()
+
+	if value.Nil {
+		return result, nil
+	}
+
+	if encoder, ok := self.encoders[valueType]; ok {
+		decoded, err := encoder.Decode(value.Encoded)
+		if err == nil && decoded != nil {
+			result.Set(reflect.ValueOf(decoded))
+		}
+		return result, err
+	}
+
+	if self.format == CassetteGob {
+		holder := reflect.New(reflect.StructOf([]reflect.StructField{{Name: "Value", Type: valueType}}))
+		err := gob.NewDecoder(bytes.NewReader(value.Raw)).DecodeValue(holder)
+		return holder.Elem().Field(0), err
+	}
+
+	err := json.Unmarshal(value.Raw, result.Addr().Interface())
+	return result, err
+}
+
+// Undoes the indentation of the cassette file, so raw values compare equal to freshly encoded ones
+func compactAll(values []cassetteValue) {
+	for i, value := range values {
+		var buffer bytes.Buffer
+		if value.Raw != nil && json.Compact(&buffer, value.Raw) == nil {
+			values[i].Raw = buffer.Bytes()
+		}
+	}
+}
+
+func isNil(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+		return value.IsNil()
+	}
+
+	return false
+}
+
+// Errors are recorded by their message, and replayed as plain errors
+type errorEncoder struct{}
+
+func (errorEncoder) Encode(value interface{}) ([]byte, error) {
+	return []byte(value.(error).Error()), nil
+}
+
+func (errorEncoder) Decode(data []byte) (interface{}, error) {
+	return errors.New(string(data)), nil
+}
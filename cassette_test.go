@@ -0,0 +1,218 @@
+package gospy_test
+
+import (
+	"errors"
+	"fmt"
+	. "github.com/cfmobile/gospy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+type lookupRequest struct {
+	Key   string
+	Limit int
+}
+
+type upperCaseEncoder struct{}
+
+func (upperCaseEncoder) Encode(value interface{}) ([]byte, error) {
+	return []byte(strings.ToUpper(value.(string))), nil
+}
+
+func (upperCaseEncoder) Decode(data []byte) (interface{}, error) {
+	return strings.ToLower(string(data)), nil
+}
+
+var _ = Describe("Cassettes", func() {
+	var dir string
+	var lookup func(lookupRequest) (string, error)
+	var panicked bool
+
+	notFound := errors.New("not found")
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "gospy-cassettes")
+		Expect(err).NotTo(HaveOccurred())
+
+		panicked = false
+		lookup = func(request lookupRequest) (string, error) {
+			if request.Key == "missing" {
+				return "", notFound
+			}
+			return "value for " + request.Key, nil
+		}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	panicRecover := func() {
+		panicked = recover() != nil
+	}
+
+	recordCalls := func(path string, options ...CassetteOption) {
+		spy := Spy(&lookup)
+		defer spy.Restore()
+
+		Expect(spy.Record(path, options...)).To(Succeed())
+
+		lookup(lookupRequest{Key: "a", Limit: 1})
+		lookup(lookupRequest{Key: "missing"})
+
+		Expect(spy.CallCount()).To(Equal(2))
+	}
+
+	replayTests := func(extension string, options ...CassetteOption) {
+		var path string
+		var subject *GoSpy
+
+		BeforeEach(func() {
+			path = filepath.Join(dir, "lookup"+extension)
+			recordCalls(path, options...)
+
+			lookup = func(lookupRequest) (string, error) {
+				return "should not be called", nil
+			}
+			subject = SpyAndReplay(&lookup, path, options...)
+		})
+
+		AfterEach(func() {
+			subject.Restore()
+		})
+
+		It("should answer calls with the recorded return values", func() {
+			value, err := lookup(lookupRequest{Key: "a", Limit: 1})
+
+			Expect(value).To(Equal("value for a"))
+			Expect(err).To(BeNil())
+		})
+
+		It("should replay recorded errors", func() {
+			value, err := lookup(lookupRequest{Key: "missing"})
+
+			Expect(value).To(BeEmpty())
+			Expect(err).To(MatchError("not found"))
+		})
+
+		It("should record the replayed calls", func() {
+			lookup(lookupRequest{Key: "a", Limit: 1})
+
+			Expect(subject.Calls()).To(Equal(CallList{{lookupRequest{Key: "a", Limit: 1}}}))
+		})
+
+		It("should panic when no recorded call matches the arguments", func() {
+			func() {
+				defer panicRecover()
+				lookup(lookupRequest{Key: "a", Limit: 2})
+			}()
+
+			Expect(panicked).To(BeTrue())
+		})
+	}
+
+	Context("when recording to a JSON cassette", func() {
+		replayTests(".json")
+	})
+
+	Context("when recording to a gob cassette", func() {
+		replayTests(".gob")
+	})
+
+	concurrentTests := func(extension string) {
+		callConcurrently := func(n int, call func(i int)) {
+			var wait sync.WaitGroup
+			for i := 0; i < n; i++ {
+				wait.Add(1)
+				go func(i int) {
+					defer wait.Done()
+					call(i)
+				}(i)
+			}
+			wait.Wait()
+		}
+
+		It("should record and replay every call", func() {
+			path := filepath.Join(dir, "concurrent"+extension)
+
+			spy := Spy(&lookup)
+			Expect(spy.Record(path)).To(Succeed())
+			callConcurrently(50, func(i int) {
+				lookup(lookupRequest{Key: fmt.Sprint(i)})
+			})
+			spy.Restore()
+
+			subject := SpyAndReplay(&lookup, path)
+			defer subject.Restore()
+
+			callConcurrently(50, func(i int) {
+				defer GinkgoRecover()
+				value, _ := lookup(lookupRequest{Key: fmt.Sprint(i)})
+				Expect(value).To(Equal(fmt.Sprintf("value for %d", i)))
+			})
+			Expect(subject.CallCount()).To(Equal(50))
+		})
+	}
+
+	Context("when calls are recorded concurrently to a JSON cassette", func() {
+		concurrentTests(".json")
+	})
+
+	Context("when calls are recorded concurrently to a gob cassette", func() {
+		concurrentTests(".gob")
+	})
+
+	Context("when a custom encoder is registered for a type", func() {
+		var path string
+
+		BeforeEach(func() {
+			path = filepath.Join(dir, "echo.json")
+			echo := func(s string) string { return s }
+
+			spy := Spy(&echo)
+			Expect(spy.Record(path, WithCassetteEncoder(reflect.TypeOf(""), upperCaseEncoder{}))).To(Succeed())
+			echo("hello")
+			spy.Restore()
+		})
+
+		It("should use the encoder to write the values", func() {
+			data, err := os.ReadFile(path)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).NotTo(ContainSubstring("hello"))
+		})
+	})
+
+	Context("when replaying a cassette recorded for a different signature", func() {
+		BeforeEach(func() {
+			path := filepath.Join(dir, "lookup.json")
+			recordCalls(path)
+
+			other := func(string) string { return "" }
+
+			defer panicRecover()
+			SpyAndReplay(&other, path)
+		})
+
+		It("should have panicked", func() {
+			Expect(panicked).To(BeTrue())
+		})
+	})
+
+	Context("when replaying a cassette that doesn't exist", func() {
+		BeforeEach(func() {
+			defer panicRecover()
+			SpyAndReplay(&lookup, filepath.Join(dir, "nothing.json"))
+		})
+
+		It("should have panicked", func() {
+			Expect(panicked).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,82 @@
+// Prints call logs written by ExportJSON(), one call per line:
+//
+//	gospy-log [-spy pattern] [-since seq] [-panics] [file...]
+//
+// Logs are read from the standard input if no file is given
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/cfmobile/gospy"
+	"io"
+	"os"
+	"regexp"
+)
+
+type filter struct {
+	spy    *regexp.Regexp
+	since  uint64
+	panics bool
+}
+
+func main() {
+	spyPattern := flag.String("spy", "", "only show the calls of spies whose name matches this pattern")
+	since := flag.Uint64("since", 0, "only show the calls with a greater sequence number")
+	panics := flag.Bool("panics", false, "only show the calls that panicked")
+	flag.Parse()
+
+	spy, err := regexp.Compile(*spyPattern)
+	if err != nil {
+		fail(fmt.Sprintf("Invalid spy pattern %q: %s", *spyPattern, err.Error()))
+	}
+	calls := filter{spy: spy, since: *since, panics: *panics}
+
+	if flag.NArg() == 0 {
+		if err := printLog(os.Stdout, os.Stdin, calls); err != nil {
+			fail(err.Error())
+		}
+		return
+	}
+
+	for _, path := range flag.Args() {
+		if err := printFile(path, calls); err != nil {
+			fail(fmt.Sprintf("%s: %s", path, err.Error()))
+		}
+	}
+}
+
+func printFile(path string, calls filter) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return printLog(os.Stdout, file, calls)
+}
+
+// Writes the calls of the log read from r that pass the filter to w
+func printLog(w io.Writer, r io.Reader, calls filter) error {
+	log, err := gospy.ReadJSONLog(r)
+	if err != nil {
+		return err
+	}
+
+	for _, call := range log.Calls {
+		if calls.matches(call) {
+			fmt.Fprintln(w, call)
+		}
+	}
+
+	return nil
+}
+
+func (self filter) matches(call gospy.JSONCall) bool {
+	return call.Seq > self.since && (call.Panicked || !self.panics) && self.spy.MatchString(call.Spy)
+}
+
+func fail(message string) {
+	fmt.Fprintln(os.Stderr, "gospy-log:", message)
+	os.Exit(1)
+}
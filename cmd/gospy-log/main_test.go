@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"github.com/cfmobile/gospy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"regexp"
+	"strings"
+)
+
+var _ = Describe("gospy-log", func() {
+	var connect func(string) error
+	var send func(string) (int, error)
+	var registry *gospy.Registry
+	var log bytes.Buffer
+	var output bytes.Buffer
+
+	BeforeEach(func() {
+		connect = func(string) error { return nil }
+		send = func(message string) (int, error) { return len(message), nil }
+
+		registry = gospy.NewRegistry()
+		registry.SpyAndFakeWithReturn(&connect, errors.New("refused")).Named("connect")
+		registry.SpyAndFakeWithFunc(&send, func(message string) (int, error) {
+			if message == "" {
+				panic("empty message")
+			}
+			return len(message), nil
+		}).Named("send")
+
+		connect("db")
+		send("a")
+		func() {
+			defer func() { recover() }()
+			send("")
+		}()
+
+		log.Reset()
+		output.Reset()
+		Expect(registry.ExportJSON(&log)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		registry.RestoreAll()
+	})
+
+	printed := func() []string {
+		return strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	}
+
+	It("should print every call of the log, one per line", func() {
+		Expect(printLog(&output, &log, filter{spy: regexp.MustCompile("")})).To(Succeed())
+
+		lines := printed()
+		Expect(lines).To(HaveLen(3))
+		Expect(lines[0]).To(HaveSuffix(`connect("db") => ("refused")`))
+		Expect(lines[1]).To(HaveSuffix(`send("a") => (1, null)`))
+		Expect(lines[2]).To(HaveSuffix(`send("") panicked: "empty message"`))
+	})
+
+	It("should only print the calls of the spies matching the pattern", func() {
+		Expect(printLog(&output, &log, filter{spy: regexp.MustCompile("^conn")})).To(Succeed())
+
+		Expect(printed()).To(ConsistOf(HaveSuffix(`connect("db") => ("refused")`)))
+	})
+
+	It("should only print the calls after the given sequence number", func() {
+		calls, err := gospy.ReadJSONLog(bytes.NewReader(log.Bytes()))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(printLog(&output, &log, filter{spy: regexp.MustCompile(""), since: calls.Calls[1].Seq})).To(Succeed())
+
+		Expect(printed()).To(ConsistOf(HaveSuffix(`send("") panicked: "empty message"`)))
+	})
+
+	It("should only print the calls that panicked", func() {
+		Expect(printLog(&output, &log, filter{spy: regexp.MustCompile(""), panics: true})).To(Succeed())
+
+		Expect(printed()).To(ConsistOf(ContainSubstring("panicked")))
+	})
+
+	It("should refuse logs of another schema", func() {
+		err := printLog(&output, strings.NewReader(`{"schema":0,"calls":[]}`), filter{spy: regexp.MustCompile("")})
+
+		Expect(err).To(MatchError(ContainSubstring("Unsupported call log schema 0")))
+		Expect(output.String()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,170 @@
+package gospy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type DiagramFormat int
+
+const (
+	Mermaid DiagramFormat = iota
+	PlantUML
+)
+
+const maxDiagramLabelLength = 80
+
+// Where diagrams take their calls from: a Recorder or a Registry
+type DiagramSource interface {
+	Entries() []RecordedCall
+}
+
+// A test that can tell whether it failed, such as *testing.T
+type FailureReportingT interface {
+	TestingT
+	Failed() bool
+	Logf(format string, args ...interface{})
+}
+
+type diagramWriter struct {
+	writer       *bufio.Writer
+	format       DiagramFormat
+	participants map[string]string
+}
+
+// Writes the calls of source as a sequence diagram, with an arrow from the
+// package of the caller to the spy for each call, and back for its returns.
+// Callers are only known to spies capturing them, see CaptureCallers()
+func WriteSequenceDiagram(w io.Writer, source DiagramSource, format DiagramFormat) error {
+	diagram := &diagramWriter{writer: bufio.NewWriter(w), format: format, participants: make(map[string]string)}
+	entries := source.Entries()
+
+	diagram.header()
+	for _, entry := range entries {
+		diagram.participant(callerPackage(entry.Call))
+		diagram.participant(entry.Spy)
+	}
+
+	for _, entry := range entries {
+		diagram.call(entry)
+	}
+	diagram.footer()
+
+	return diagram.writer.Flush()
+}
+
+// Saves the sequence diagram of source to path once the test is over, if it failed
+func SaveSequenceDiagramOnFailure(t FailureReportingT, path string, source DiagramSource, format DiagramFormat) {
+	t.Cleanup(func() {
+		if !t.Failed() {
+			return
+		}
+
+		if err := saveSequenceDiagram(path, source, format); err != nil {
+			t.Logf("Failed to save the sequence diagram to %s: %s", path, err.Error())
+			return
+		}
+
+		t.Logf("Sequence diagram of the calls saved to %s", path)
+	})
+}
+
+func saveSequenceDiagram(path string, source DiagramSource, format DiagramFormat) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := WriteSequenceDiagram(file, source, format); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func (self *diagramWriter) header() {
+	if self.format == PlantUML {
+		fmt.Fprintln(self.writer, "@startuml")
+	} else {
+		fmt.Fprintln(self.writer, "sequenceDiagram")
+	}
+}
+
+func (self *diagramWriter) footer() {
+	if self.format == PlantUML {
+		fmt.Fprintln(self.writer, "@enduml")
+	}
+}
+
+// Participants get short ids, as names hold characters the formats don't accept in ids
+func (self *diagramWriter) participant(name string) {
+	if _, ok := self.participants[name]; ok {
+		return
+	}
+
+	id := fmt.Sprintf("p%d", len(self.participants))
+	self.participants[name] = id
+
+	if self.format == PlantUML {
+		fmt.Fprintf(self.writer, "participant %q as %s\n", name, id)
+	} else {
+		fmt.Fprintf(self.writer, "    participant %s as %s\n", id, self.label(name))
+	}
+}
+
+func (self *diagramWriter) call(entry RecordedCall) {
+	caller, spy := self.participants[callerPackage(entry.Call)], self.participants[entry.Spy]
+	arguments := self.label(entry.Spy + FormatArgs(entry.Call.Args))
+
+	if self.format == PlantUML {
+		fmt.Fprintf(self.writer, "%s -> %s : %s\n", caller, spy, arguments)
+	} else {
+		fmt.Fprintf(self.writer, "    %s->>%s: %s\n", caller, spy, arguments)
+	}
+
+	switch {
+	case entry.Call.Panicked:
+		note := self.label("panicked: " + FormatValue(entry.Call.PanicValue))
+		if self.format == PlantUML {
+			fmt.Fprintf(self.writer, "note over %s : %s\n", spy, note)
+		} else {
+			fmt.Fprintf(self.writer, "    Note over %s: %s\n", spy, note)
+		}
+	case len(entry.Call.Returns) > 0:
+		returns := self.label(FormatArgs(entry.Call.Returns))
+		if self.format == PlantUML {
+			fmt.Fprintf(self.writer, "%s --> %s : %s\n", spy, caller, returns)
+		} else {
+			fmt.Fprintf(self.writer, "    %s-->>%s: %s\n", spy, caller, returns)
+		}
+	}
+}
+
+// Keeps labels on one line, abbreviated, without the characters ending statements in Mermaid
+func (self *diagramWriter) label(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if self.format == Mermaid {
+		text = strings.Replace(text, ";", "#59;", -1)
+	}
+
+	return truncate(text, maxDiagramLabelLength)
+}
+
+// The last element of the package path of the caller, such as "billing"
+func callerPackage(call Call) string {
+	if call.Origin == nil {
+		return "caller"
+	}
+
+	name := call.Origin.Caller.Function
+	name = name[strings.LastIndex(name, "/")+1:]
+	if dot := strings.Index(name, "."); dot >= 0 {
+		name = name[:dot]
+	}
+
+	return name
+}
@@ -0,0 +1,147 @@
+package gospy_test
+
+import (
+	"bytes"
+	"errors"
+	. "github.com/cfmobile/gospy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type failingT struct {
+	failed   bool
+	cleanups []func()
+	logs     []string
+}
+
+func (self *failingT) Name() string                            { return "failingT" }
+func (self *failingT) Cleanup(cleanup func())                  { self.cleanups = append(self.cleanups, cleanup) }
+func (self *failingT) Failed() bool                            { return self.failed }
+func (self *failingT) Logf(format string, args ...interface{}) { self.logs = append(self.logs, format) }
+
+func (self *failingT) finish() {
+	for _, cleanup := range self.cleanups {
+		cleanup()
+	}
+}
+
+var _ = Describe("Sequence diagrams", func() {
+	var connect func(string) error
+	var send func(string) (int, error)
+	var registry *Registry
+	var output bytes.Buffer
+
+	BeforeEach(func() {
+		connect = func(string) error { return nil }
+		send = func(message string) (int, error) { return len(message), nil }
+
+		registry = NewRegistry()
+		registry.Spy(&connect).Named("connect")
+		registry.SpyAndFakeWithReturn(&send, 0, errors.New("broken pipe")).Named("send")
+		output.Reset()
+	})
+
+	AfterEach(func() {
+		registry.RestoreAll()
+	})
+
+	It("should draw the calls of a registry as a Mermaid diagram", func() {
+		connect("db")
+		send("hello")
+
+		Expect(WriteSequenceDiagram(&output, registry, Mermaid)).To(Succeed())
+		Expect(output.String()).To(Equal("sequenceDiagram\n" +
+			"    participant p0 as caller\n" +
+			"    participant p1 as connect\n" +
+			"    participant p2 as send\n" +
+			"    p0->>p1: connect(\"db\")\n" +
+			"    p1-->>p0: (nil)\n" +
+			"    p0->>p2: send(\"hello\")\n" +
+			"    p2-->>p0: (0, error(\"broken pipe\"))\n"))
+	})
+
+	It("should draw the calls of a recorder as a PlantUML diagram", func() {
+		recorder := NewRecorder().Attach(registry.Lookup("send"))
+		connect("db")
+		send("hello")
+
+		Expect(WriteSequenceDiagram(&output, recorder, PlantUML)).To(Succeed())
+		Expect(output.String()).To(Equal("@startuml\n" +
+			"participant \"caller\" as p0\n" +
+			"participant \"send\" as p1\n" +
+			"p0 -> p1 : send(\"hello\")\n" +
+			"p1 --> p0 : (0, error(\"broken pipe\"))\n" +
+			"@enduml\n"))
+	})
+
+	It("should start arrows from the package of the caller when callers are captured", func() {
+		registry.Lookup("connect").SetRecording(CaptureCallers())
+		connect("db")
+
+		Expect(WriteSequenceDiagram(&output, registry, Mermaid)).To(Succeed())
+		Expect(output.String()).To(ContainSubstring("participant p0 as gospy_test\n"))
+	})
+
+	It("should add a note for panics", func() {
+		registry.SpyAndFakeWithFunc(&connect, func(string) error { panic("no route") }).Named("connect")
+		func() {
+			defer func() { recover() }()
+			connect("db")
+		}()
+
+		Expect(WriteSequenceDiagram(&output, registry, Mermaid)).To(Succeed())
+		Expect(output.String()).To(ContainSubstring("    Note over p1: panicked: \"no route\"\n"))
+		Expect(output.String()).ToNot(ContainSubstring("-->>"))
+	})
+
+	It("should abbreviate long labels and keep them on one line", func() {
+		connect(strings.Repeat("a", 200) + "\n;")
+
+		Expect(WriteSequenceDiagram(&output, registry, Mermaid)).To(Succeed())
+		for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+			Expect(len(line)).To(BeNumerically("<", 100))
+		}
+	})
+
+	Describe("SaveSequenceDiagramOnFailure", func() {
+		var directory string
+		var path string
+
+		BeforeEach(func() {
+			var err error
+			directory, err = ioutil.TempDir("", "gospy-diagram")
+			Expect(err).ToNot(HaveOccurred())
+			path = filepath.Join(directory, "calls.mmd")
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(directory)
+		})
+
+		It("should save the diagram when the test failed", func() {
+			t := &failingT{failed: true}
+			SaveSequenceDiagramOnFailure(t, path, registry, Mermaid)
+			connect("db")
+			t.finish()
+
+			content, err := ioutil.ReadFile(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring("p0->>p1: connect(\"db\")"))
+			Expect(t.logs).To(HaveLen(1))
+		})
+
+		It("should not save anything when the test passed", func() {
+			t := &failingT{}
+			SaveSequenceDiagramOnFailure(t, path, registry, Mermaid)
+			connect("db")
+			t.finish()
+
+			_, err := os.Stat(path)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+})
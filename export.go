@@ -0,0 +1,136 @@
+package gospy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Version of the layout written by ExportJSON(). It changes whenever a field
+// is removed or changes meaning, so tools can refuse logs they can't read
+const JSONLogSchema = 1
+
+// Calls exported by ExportJSON(), ordered by sequence number
+type JSONLog struct {
+	Schema int        `json:"schema"`
+	Calls  []JSONCall `json:"calls"`
+}
+
+// Values are kept as JSON. Errors are written as their message, and values
+// JSON can't hold as a string formatted with %#v
+type JSONCall struct {
+	Seq      uint64            `json:"seq"`
+	Index    int               `json:"index"`
+	Time     time.Time         `json:"time"`
+	Spy      string            `json:"spy"`
+	Args     []json.RawMessage `json:"args"`
+	Returns  []json.RawMessage `json:"returns,omitempty"`
+	Panicked bool              `json:"panicked,omitempty"`
+	Panic    json.RawMessage   `json:"panic,omitempty"`
+}
+
+// Writes the calls recorded by the spy as a JSON log
+func (self *GoSpy) ExportJSON(w io.Writer) error {
+	name := self.Name()
+
+	var entries []RecordedCall
+	for _, call := range self.Query() {
+		entries = append(entries, RecordedCall{Spy: name, Call: call})
+	}
+
+	return exportJSON(w, entries)
+}
+
+// Writes the calls recorded by every spy of the registry as one JSON log
+func (self *Registry) ExportJSON(w io.Writer) error {
+	return exportJSON(w, self.Entries())
+}
+
+// Reads a log written by ExportJSON(), refusing other schema versions
+func ReadJSONLog(r io.Reader) (*JSONLog, error) {
+	var log JSONLog
+	if err := json.NewDecoder(r).Decode(&log); err != nil {
+		return nil, err
+	}
+
+	if log.Schema != JSONLogSchema {
+		return nil, errors.New(fmt.Sprintf("Unsupported call log schema %d, expected %d", log.Schema, JSONLogSchema))
+	}
+
+	return &log, nil
+}
+
+func (self JSONCall) String() string {
+	text := fmt.Sprintf("#%d %s %s(%s)", self.Seq, self.Time.Format("15:04:05.000"), self.Spy, joinJSON(self.Args))
+
+	if self.Panicked {
+		return text + " panicked: " + string(self.Panic)
+	}
+
+	if self.Returns != nil {
+		return text + " => (" + joinJSON(self.Returns) + ")"
+	}
+
+	return text
+}
+
+func exportJSON(w io.Writer, entries []RecordedCall) error {
+	log := JSONLog{Schema: JSONLogSchema, Calls: make([]JSONCall, len(entries))}
+
+	for i, entry := range entries {
+		log.Calls[i] = JSONCall{
+			Seq:      entry.Call.Seq,
+			Index:    entry.Call.Index,
+			Time:     entry.Call.Time,
+			Spy:      entry.Spy,
+			Args:     marshalValues(entry.Call.Args),
+			Returns:  marshalValues(entry.Call.Returns),
+			Panicked: entry.Call.Panicked,
+		}
+
+		if entry.Call.Panicked {
+			log.Calls[i].Panic = marshalValue(entry.Call.PanicValue)
+		}
+	}
+
+	return json.NewEncoder(w).Encode(log)
+}
+
+func marshalValues(values []interface{}) []json.RawMessage {
+	if values == nil {
+		return nil
+	}
+
+	marshalled := make([]json.RawMessage, len(values))
+	for i, value := range values {
+		marshalled[i] = marshalValue(value)
+	}
+
+	return marshalled
+}
+
+func marshalValue(value interface{}) json.RawMessage {
+	if err, ok := value.(error); ok && !isNil(reflect.ValueOf(err)) {
+		value = err.Error()
+	}
+
+	marshalled, err := json.Marshal(value)
+	if err != nil {
+		marshalled, _ = json.Marshal(fmt.Sprintf("%#v", value))
+	}
+
+	return marshalled
+}
+
+func joinJSON(values []json.RawMessage) string {
+	texts := make([]string, len(values))
+	for i, value := range values {
+		texts[i] = string(value)
+	}
+
+	return strings.Join(texts, ", ")
+}
@@ -0,0 +1,106 @@
+package gospy_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	. "github.com/cfmobile/gospy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"math"
+	"strings"
+	"time"
+)
+
+var _ = Describe("JSON export", func() {
+	var connect func(string) error
+	var send func(interface{}) (int, error)
+	var registry *Registry
+	var output bytes.Buffer
+
+	BeforeEach(func() {
+		connect = func(string) error { return nil }
+		send = func(interface{}) (int, error) { return 0, nil }
+
+		registry = NewRegistry()
+		registry.SpyAndFakeWithReturn(&connect, errors.New("refused")).Named("connect")
+		registry.SpyAndFakeWithReturn(&send, 2, nil).Named("send")
+		output.Reset()
+	})
+
+	AfterEach(func() {
+		registry.RestoreAll()
+	})
+
+	readLog := func() *JSONLog {
+		log, err := ReadJSONLog(&output)
+		Expect(err).ToNot(HaveOccurred())
+		return log
+	}
+
+	It("should write every call of the registry with its sequence number, time, spy, args and returns", func() {
+		before := time.Now()
+		connect("db")
+		send(map[string]int{"id": 1})
+
+		Expect(registry.ExportJSON(&output)).To(Succeed())
+		Expect(output.String()).To(HavePrefix(`{"schema":1,"calls":[`))
+
+		log := readLog()
+		Expect(log.Calls).To(HaveLen(2))
+		Expect(log.Calls[0].Spy).To(Equal("connect"))
+		Expect(log.Calls[0].Seq).To(BeNumerically("<", log.Calls[1].Seq))
+		Expect(log.Calls[0].Time).To(BeTemporally(">=", before))
+		Expect(log.Calls[1].Args).To(Equal([]json.RawMessage{json.RawMessage(`{"id":1}`)}))
+		Expect(log.Calls[1].String()).To(HaveSuffix(`send({"id":1}) => (2, null)`))
+	})
+
+	It("should write errors as their message", func() {
+		connect("db")
+
+		Expect(registry.ExportJSON(&output)).To(Succeed())
+		Expect(readLog().Calls[0].Returns).To(Equal([]json.RawMessage{json.RawMessage(`"refused"`)}))
+	})
+
+	It("should fall back to %#v for values JSON can't hold", func() {
+		send(make(chan int))
+		send(math.NaN())
+
+		Expect(registry.ExportJSON(&output)).To(Succeed())
+		calls := readLog().Calls
+		Expect(string(calls[0].Args[0])).To(HavePrefix(`"(chan int)(0x`))
+		Expect(string(calls[1].Args[0])).To(Equal(`"NaN"`))
+	})
+
+	It("should write the panic of calls that panicked", func() {
+		registry.SpyAndFakeWithFunc(&connect, func(string) error { panic("no route") }).Named("connect")
+		func() {
+			defer func() { recover() }()
+			connect("db")
+		}()
+
+		Expect(registry.ExportJSON(&output)).To(Succeed())
+		call := readLog().Calls[0]
+		Expect(call.Panicked).To(BeTrue())
+		Expect(string(call.Panic)).To(Equal(`"no route"`))
+		Expect(call.Returns).To(BeNil())
+		Expect(call.String()).To(HaveSuffix(`connect("db") panicked: "no route"`))
+	})
+
+	It("should export the calls of a single spy", func() {
+		connect("db")
+		send("hello")
+
+		Expect(registry.Lookup("send").ExportJSON(&output)).To(Succeed())
+		log := readLog()
+		Expect(log.Calls).To(HaveLen(1))
+		Expect(log.Calls[0].Spy).To(Equal("send"))
+		Expect(log.Calls[0].Index).To(Equal(0))
+	})
+
+	It("should refuse logs of another schema version", func() {
+		_, err := ReadJSONLog(strings.NewReader(`{"schema":99,"calls":[]}`))
+
+		Expect(err).To(MatchError("Unsupported call log schema 99, expected 1"))
+	})
+})
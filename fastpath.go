@@ -0,0 +1,334 @@
+package gospy
+
+import (
+	"context"
+	"reflect"
+)
+
+// Builds the wrapper installed for a target signature without reflect.MakeFunc,
+// and the fake answering a constant set of results
+type fastSignature struct {
+	wrap     func(spy *GoSpy) interface{}
+	constant func(results []reflect.Value) interface{}
+}
+
+// Signatures common enough to be worth spying on without reflection. Others
+// go through reflect.MakeFunc
+var fastSignatures = map[reflect.Type]fastSignature{}
+
+func init() {
+	registerFastSignature(fast00())
+	registerFastSignature(fast01[bool]())
+	registerFastSignature(fast01[int]())
+	registerFastSignature(fast01[string]())
+	registerFastSignature(fast01[error]())
+	registerFastSignature(fast01[interface{}]())
+	registerFastSignature(fast02[string, error]())
+	registerFastSignature(fast02[int, error]())
+	registerFastSignature(fast02[interface{}, error]())
+	registerFastSignature(fast10[string]())
+	registerFastSignature(fast10[int]())
+	registerFastSignature(fast10[error]())
+	registerFastSignature(fast10[interface{}]())
+	registerFastSignature(fast11[string, string]())
+	registerFastSignature(fast11[string, bool]())
+	registerFastSignature(fast11[string, int]())
+	registerFastSignature(fast11[string, error]())
+	registerFastSignature(fast11[string, interface{}]())
+	registerFastSignature(fast11[int, int]())
+	registerFastSignature(fast11[int, string]())
+	registerFastSignature(fast11[int, error]())
+	registerFastSignature(fast11[interface{}, error]())
+	registerFastSignature(fast11[context.Context, error]())
+	registerFastSignature(fast11[[]byte, error]())
+	registerFastSignature(fast12[string, string, error]())
+	registerFastSignature(fast12[string, int, error]())
+	registerFastSignature(fast12[string, []byte, error]())
+	registerFastSignature(fast12[string, interface{}, error]())
+	registerFastSignature(fast12[string, interface{}, bool]())
+	registerFastSignature(fast12[int, string, error]())
+	registerFastSignature(fast12[[]byte, int, error]())
+	registerFastSignature(fast12[context.Context, interface{}, error]())
+	registerFastSignature(fast20[string, string]())
+	registerFastSignature(fast20[string, interface{}]())
+	registerFastSignature(fast21[string, string, string]())
+	registerFastSignature(fast21[string, string, error]())
+	registerFastSignature(fast21[string, interface{}, error]())
+	registerFastSignature(fast21[int, int, int]())
+	registerFastSignature(fast21[context.Context, string, error]())
+	registerFastSignature(fast21[context.Context, interface{}, error]())
+	registerFastSignature(fastVariadic0[interface{}]())
+	registerFastSignature(fastVariadic1[string, interface{}]())
+	registerFastSignature(fastVariadic1[string, string]())
+}
+
+func registerFastSignature(prototype interface{}, signature fastSignature) {
+	fastSignatures[reflect.TypeOf(prototype)] = signature
+}
+
+func fast00() (interface{}, fastSignature) {
+	return (func())(nil), fastSignature{
+		wrap: func(spy *GoSpy) interface{} {
+			return func() {
+				record, behaviour, fast := spy.startCall(func() ArgList {
+					return ArgList{}
+				})
+				defer spy.finishCall(record, func() []interface{} {
+					return []interface{}{}
+				})
+
+				if fast != nil {
+					fast().(func())()
+					return
+				}
+				behaviour(nil)
+			}
+		},
+		constant: func([]reflect.Value) interface{} {
+			return func() {}
+		},
+	}
+}
+
+func fast01[R any]() (interface{}, fastSignature) {
+	return (func() R)(nil), fastSignature{
+		wrap: func(spy *GoSpy) interface{} {
+			return func() (r R) {
+				record, behaviour, fast := spy.startCall(func() ArgList {
+					return ArgList{}
+				})
+				defer spy.finishCall(record, func() []interface{} {
+					return []interface{}{r}
+				})
+
+				if fast != nil {
+					return fast().(func() R)()
+				}
+				results := behaviour(nil)
+				return valueAs[R](results[0])
+			}
+		},
+		constant: func(results []reflect.Value) interface{} {
+			r := valueAs[R](results[0])
+			return func() R {
+				return r
+			}
+		},
+	}
+}
+
+func fast02[R1, R2 any]() (interface{}, fastSignature) {
+	return (func() (R1, R2))(nil), fastSignature{
+		wrap: func(spy *GoSpy) interface{} {
+			return func() (r1 R1, r2 R2) {
+				record, behaviour, fast := spy.startCall(func() ArgList {
+					return ArgList{}
+				})
+				defer spy.finishCall(record, func() []interface{} {
+					return []interface{}{r1, r2}
+				})
+
+				if fast != nil {
+					return fast().(func() (R1, R2))()
+				}
+				results := behaviour(nil)
+				return valueAs[R1](results[0]), valueAs[R2](results[1])
+			}
+		},
+		constant: func(results []reflect.Value) interface{} {
+			r1, r2 := valueAs[R1](results[0]), valueAs[R2](results[1])
+			return func() (R1, R2) {
+				return r1, r2
+			}
+		},
+	}
+}
+
+func fast10[A any]() (interface{}, fastSignature) {
+	return (func(A))(nil), fastSignature{
+		wrap: func(spy *GoSpy) interface{} {
+			return func(a A) {
+				record, behaviour, fast := spy.startCall(func() ArgList {
+					return ArgList{a}
+				})
+				defer spy.finishCall(record, func() []interface{} {
+					return []interface{}{}
+				})
+
+				if fast != nil {
+					fast().(func(A))(a)
+					return
+				}
+				behaviour([]reflect.Value{valueOf(a)})
+			}
+		},
+		constant: func([]reflect.Value) interface{} {
+			return func(A) {}
+		},
+	}
+}
+
+func fast11[A, R any]() (interface{}, fastSignature) {
+	return (func(A) R)(nil), fastSignature{
+		wrap: func(spy *GoSpy) interface{} {
+			return func(a A) (r R) {
+				record, behaviour, fast := spy.startCall(func() ArgList {
+					return ArgList{a}
+				})
+				defer spy.finishCall(record, func() []interface{} {
+					return []interface{}{r}
+				})
+
+				if fast != nil {
+					return fast().(func(A) R)(a)
+				}
+				results := behaviour([]reflect.Value{valueOf(a)})
+				return valueAs[R](results[0])
+			}
+		},
+		constant: func(results []reflect.Value) interface{} {
+			r := valueAs[R](results[0])
+			return func(A) R {
+				return r
+			}
+		},
+	}
+}
+
+func fast12[A, R1, R2 any]() (interface{}, fastSignature) {
+	return (func(A) (R1, R2))(nil), fastSignature{
+		wrap: func(spy *GoSpy) interface{} {
+			return func(a A) (r1 R1, r2 R2) {
+				record, behaviour, fast := spy.startCall(func() ArgList {
+					return ArgList{a}
+				})
+				defer spy.finishCall(record, func() []interface{} {
+					return []interface{}{r1, r2}
+				})
+
+				if fast != nil {
+					return fast().(func(A) (R1, R2))(a)
+				}
+				results := behaviour([]reflect.Value{valueOf(a)})
+				return valueAs[R1](results[0]), valueAs[R2](results[1])
+			}
+		},
+		constant: func(results []reflect.Value) interface{} {
+			r1, r2 := valueAs[R1](results[0]), valueAs[R2](results[1])
+			return func(A) (R1, R2) {
+				return r1, r2
+			}
+		},
+	}
+}
+
+func fast20[A, B any]() (interface{}, fastSignature) {
+	return (func(A, B))(nil), fastSignature{
+		wrap: func(spy *GoSpy) interface{} {
+			return func(a A, b B) {
+				record, behaviour, fast := spy.startCall(func() ArgList {
+					return ArgList{a, b}
+				})
+				defer spy.finishCall(record, func() []interface{} {
+					return []interface{}{}
+				})
+
+				if fast != nil {
+					fast().(func(A, B))(a, b)
+					return
+				}
+				behaviour([]reflect.Value{valueOf(a), valueOf(b)})
+			}
+		},
+		constant: func([]reflect.Value) interface{} {
+			return func(A, B) {}
+		},
+	}
+}
+
+func fast21[A, B, R any]() (interface{}, fastSignature) {
+	return (func(A, B) R)(nil), fastSignature{
+		wrap: func(spy *GoSpy) interface{} {
+			return func(a A, b B) (r R) {
+				record, behaviour, fast := spy.startCall(func() ArgList {
+					return ArgList{a, b}
+				})
+				defer spy.finishCall(record, func() []interface{} {
+					return []interface{}{r}
+				})
+
+				if fast != nil {
+					return fast().(func(A, B) R)(a, b)
+				}
+				results := behaviour([]reflect.Value{valueOf(a), valueOf(b)})
+				return valueAs[R](results[0])
+			}
+		},
+		constant: func(results []reflect.Value) interface{} {
+			r := valueAs[R](results[0])
+			return func(A, B) R {
+				return r
+			}
+		},
+	}
+}
+
+// Variadic arguments are recorded as a slice, as the reflection path does
+func fastVariadic0[V any]() (interface{}, fastSignature) {
+	return (func(...V))(nil), fastSignature{
+		wrap: func(spy *GoSpy) interface{} {
+			return func(v ...V) {
+				record, behaviour, fast := spy.startCall(func() ArgList {
+					return ArgList{v}
+				})
+				defer spy.finishCall(record, func() []interface{} {
+					return []interface{}{}
+				})
+
+				if fast != nil {
+					fast().(func(...V))(v...)
+					return
+				}
+				behaviour([]reflect.Value{reflect.ValueOf(v)})
+			}
+		},
+		constant: func([]reflect.Value) interface{} {
+			return func(...V) {}
+		},
+	}
+}
+
+func fastVariadic1[A, V any]() (interface{}, fastSignature) {
+	return (func(A, ...V))(nil), fastSignature{
+		wrap: func(spy *GoSpy) interface{} {
+			return func(a A, v ...V) {
+				record, behaviour, fast := spy.startCall(func() ArgList {
+					return ArgList{a, v}
+				})
+				defer spy.finishCall(record, func() []interface{} {
+					return []interface{}{}
+				})
+
+				if fast != nil {
+					fast().(func(A, ...V))(a, v...)
+					return
+				}
+				behaviour([]reflect.Value{valueOf(a), reflect.ValueOf(v)})
+			}
+		},
+		constant: func([]reflect.Value) interface{} {
+			return func(A, ...V) {}
+		},
+	}
+}
+
+// Keeps the static type of v, so nil interfaces become typed zero values as reflect.MakeFunc passes them
+func valueOf[T any](v T) reflect.Value {
+	return reflect.ValueOf(&v).Elem()
+}
+
+func valueAs[T any](value reflect.Value) T {
+	var v T
+	reflect.ValueOf(&v).Elem().Set(value)
+	return v
+}
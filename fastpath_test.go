@@ -0,0 +1,141 @@
+package gospy_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	. "github.com/cfmobile/gospy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Spies on common signatures", func() {
+	var lookup func(string) (string, error)
+	var logf func(string, ...interface{})
+	var check func(context.Context) error
+	var logged []string
+
+	BeforeEach(func() {
+		logged = nil
+		lookup = func(key string) (string, error) {
+			if key == "" {
+				panic("empty key")
+			}
+			return "value of " + key, nil
+		}
+		logf = func(format string, args ...interface{}) {
+			logged = append(logged, fmt.Sprintf(format, args...))
+		}
+		check = func(context.Context) error {
+			return errors.New("unchecked")
+		}
+	})
+
+	It("should record the arguments and returns of pass-through calls", func() {
+		spy := Spy(&lookup)
+		defer spy.Restore()
+
+		Expect(lookup("a")).To(Equal("value of a"))
+		Expect(spy.Calls()).To(Equal(CallList{{"a"}}))
+		Expect(spy.String()).To(ContainSubstring(`#0 ("a") => ("value of a", nil)`))
+	})
+
+	It("should record panics and re-raise them", func() {
+		spy := Spy(&lookup)
+		defer spy.Restore()
+
+		Expect(func() { lookup("") }).To(Panic())
+		Expect(spy.String()).To(ContainSubstring(`#0 ("") panicked: "empty key"`))
+	})
+
+	It("should answer with the fake return values", func() {
+		spy := SpyAndFakeWithReturn(&lookup, "fake", errors.New("failed"))
+		defer spy.Restore()
+
+		value, err := lookup("a")
+		Expect(value).To(Equal("fake"))
+		Expect(err).To(MatchError("failed"))
+	})
+
+	It("should answer with zero values, including nil interfaces, when faked", func() {
+		spy := SpyAndFake(&check)
+		defer spy.Restore()
+
+		Expect(check(nil)).To(BeNil())
+		Expect(spy.Calls()).To(Equal(CallList{{nil}}))
+	})
+
+	It("should call the fake function", func() {
+		spy := SpyAndFakeWithFunc(&lookup, func(key string) (string, error) {
+			return "from func " + key, nil
+		})
+		defer spy.Restore()
+
+		Expect(lookup("a")).To(Equal("from func a"))
+	})
+
+	It("should pass variadic arguments through unchanged, recording them as a slice", func() {
+		spy := Spy(&logf)
+		defer spy.Restore()
+
+		logf("%s=%d", "a", 1)
+		Expect(logged).To(Equal([]string{"a=1"}))
+		Expect(spy.Calls()).To(Equal(CallList{{"%s=%d", []interface{}{"a", 1}}}))
+	})
+
+	It("should delegate to the outer spy when nested", func() {
+		outer := SpyAndFakeWithReturn(&lookup, "outer fake", nil)
+		inner := Spy(&lookup)
+		defer outer.Restore()
+		defer inner.Restore()
+
+		Expect(lookup("a")).To(Equal("outer fake"))
+		Expect(inner.CallCount()).To(Equal(1))
+		Expect(outer.CallCount()).To(Equal(1))
+	})
+
+	It("should keep calling the behaviour while paused, without counting", func() {
+		spy := SpyAndFakeWithReturn(&lookup, "fake", nil)
+		defer spy.Restore()
+
+		spy.PauseAndPassThrough()
+		Expect(lookup("a")).To(Equal("value of a"))
+		spy.Resume()
+		Expect(lookup("a")).To(Equal("fake"))
+
+		Expect(spy.CallCount()).To(Equal(1))
+	})
+})
+
+var _ = Describe("Spies on variadic functions", func() {
+	var join func(string, ...string) string
+
+	BeforeEach(func() {
+		join = func(sep string, parts ...string) string {
+			result := ""
+			for i, part := range parts {
+				if i > 0 {
+					result += sep
+				}
+				result += part
+			}
+			return result
+		}
+	})
+
+	It("should pass the variadic arguments through to the original", func() {
+		spy := Spy(&join)
+		defer spy.Restore()
+
+		Expect(join("-", "a", "b")).To(Equal("a-b"))
+	})
+
+	It("should pass the variadic arguments to the fake function", func() {
+		spy := SpyAndFakeWithFunc(&join, func(sep string, parts ...string) string {
+			return fmt.Sprint(len(parts))
+		})
+		defer spy.Restore()
+
+		Expect(join("-", "a", "b", "c")).To(Equal("3"))
+	})
+})
@@ -0,0 +1,194 @@
+package gospy
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+const (
+	maxFormattedDepth    = 3
+	maxFormattedElements = 8
+	maxFormattedLength   = 120
+)
+
+func (self *GoSpy) String() string {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	var builder strings.Builder
+
+	fmt.Fprintf(&builder, "GoSpy on %+v: %d %s", self.mock.target.Type(), self.count, pluralise(self.count, "call", "calls"))
+	if len(self.calls) < self.count {
+		fmt.Fprintf(&builder, " (%d recorded)", len(self.calls))
+	}
+
+	for i, args := range self.calls {
+		record := self.records[i]
+		fmt.Fprintf(&builder, "\n  #%d %s", record.index, FormatArgs(args))
+
+		if record.panicked {
+			fmt.Fprintf(&builder, " panicked: %s", FormatValue(record.panicValue))
+		} else if record.returned && len(record.returns) > 0 {
+			fmt.Fprintf(&builder, " => %s", FormatArgs(record.returns))
+		}
+
+		if record.origin != nil {
+			fmt.Fprintf(&builder, " from %s", record.origin.Caller)
+		}
+	}
+
+	return builder.String()
+}
+
+func (self *GoSpy) Format(state fmt.State, verb rune) {
+	switch verb {
+	case 'v', 's':
+		fmt.Fprint(state, self.String())
+	default:
+		fmt.Fprintf(state, "%%!%c(*gospy.GoSpy)", verb)
+	}
+}
+
+// Renders a list of values as a parenthesised, comma separated list
+func FormatArgs(args []interface{}) string {
+	formatted := make([]string, len(args))
+	for i, arg := range args {
+		formatted[i] = FormatValue(arg)
+	}
+
+	return "(" + strings.Join(formatted, ", ") + ")"
+}
+
+// Renders a value for humans: pointers are dereferenced, errors and Stringers
+// show their text, funcs their name, and long values are truncated
+func FormatValue(value interface{}) string {
+	return truncate(formatValue(reflect.ValueOf(value), 0), maxFormattedLength)
+}
+
+func formatValue(value reflect.Value, depth int) string {
+	if !value.IsValid() {
+		return "nil"
+	}
+
+	if value.CanInterface() && !isNil(value) {
+		if err, ok := value.Interface().(error); ok {
+			return fmt.Sprintf("error(%q)", err.Error())
+		}
+		if stringer, ok := value.Interface().(fmt.Stringer); ok && value.Kind() != reflect.Interface {
+			return stringer.String()
+		}
+	}
+
+	if depth > maxFormattedDepth {
+		return "..."
+	}
+
+	switch value.Kind() {
+	case reflect.Interface:
+		if value.IsNil() {
+			return "nil"
+		}
+		return formatValue(value.Elem(), depth)
+
+	case reflect.Ptr:
+		if value.IsNil() {
+			return "nil"
+		}
+		return "&" + formatValue(value.Elem(), depth)
+
+	case reflect.String:
+		return fmt.Sprintf("%q", truncate(value.String(), maxFormattedLength))
+
+	case reflect.Func:
+		if value.IsNil() {
+			return "nil"
+		}
+		if fn := runtime.FuncForPC(value.Pointer()); fn != nil {
+			return "func " + fn.Name()
+		}
+		return fmt.Sprintf("%+v", value.Type())
+
+	case reflect.Struct:
+		var fields []string
+		for i := 0; i < value.NumField(); i++ {
+			fields = append(fields, fmt.Sprintf("%s: %s", value.Type().Field(i).Name, formatValue(value.Field(i), depth+1)))
+		}
+		return fmt.Sprintf("%+v{%s}", value.Type(), strings.Join(fields, ", "))
+
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return "nil"
+		}
+		var elements []string
+		for i := 0; i < value.Len() && i < maxFormattedElements; i++ {
+			elements = append(elements, formatValue(value.Index(i), depth+1))
+		}
+		return "[" + strings.Join(elements, ", ") + moreElements(value.Len()) + "]"
+
+	case reflect.Map:
+		if value.IsNil() {
+			return "nil"
+		}
+		var entries []string
+		for _, key := range value.MapKeys() {
+			entries = append(entries, fmt.Sprintf("%s: %s", formatValue(key, depth+1), formatValue(value.MapIndex(key), depth+1)))
+		}
+		sort.Strings(entries)
+		if len(entries) > maxFormattedElements {
+			entries = entries[:maxFormattedElements]
+		}
+		return "map[" + strings.Join(entries, ", ") + moreElements(value.Len()) + "]"
+	}
+
+	if value.CanInterface() {
+		return fmt.Sprintf("%v", value.Interface())
+	}
+
+	// Unexported struct fields can't be interfaced, but their underlying values can still be shown
+	switch value.Kind() {
+	case reflect.Bool:
+		return fmt.Sprintf("%v", value.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fmt.Sprintf("%d", value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return fmt.Sprintf("%d", value.Uint())
+	case reflect.Float32, reflect.Float64:
+		return fmt.Sprintf("%v", value.Float())
+	}
+
+	return fmt.Sprintf("<%+v>", value.Type())
+}
+
+func moreElements(length int) string {
+	if length <= maxFormattedElements {
+		return ""
+	}
+
+	return fmt.Sprintf(", ... +%d more", length-maxFormattedElements)
+}
+
+func truncate(text string, length int) string {
+	if len(text) <= length {
+		return text
+	}
+
+	for length > 0 && !utf8.RuneStart(text[length]) {
+		length--
+	}
+
+	return text[:length] + "..."
+}
+
+func pluralise(count int, singular string, plural string) string {
+	if count == 1 {
+		return singular
+	}
+
+	return plural
+}
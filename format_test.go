@@ -0,0 +1,95 @@
+package gospy_test
+
+import (
+	"errors"
+	"fmt"
+	. "github.com/cfmobile/gospy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"strings"
+)
+
+type formattedUser struct {
+	Name    string
+	Manager *formattedUser
+}
+
+func formattedHelper() {}
+
+var _ = Describe("Formatting", func() {
+
+	Describe("FormatValue", func() {
+		It("should quote strings", func() {
+			Expect(FormatValue("abc")).To(Equal(`"abc"`))
+		})
+
+		It("should show nil values as nil", func() {
+			Expect(FormatValue(nil)).To(Equal("nil"))
+			Expect(FormatValue((*formattedUser)(nil))).To(Equal("nil"))
+		})
+
+		It("should show struct fields and dereference pointers", func() {
+			user := &formattedUser{Name: "ann", Manager: &formattedUser{Name: "bob"}}
+
+			Expect(FormatValue(user)).To(Equal(`&gospy_test.formattedUser{Name: "ann", Manager: &gospy_test.formattedUser{Name: "bob", Manager: nil}}`))
+		})
+
+		It("should show the message of errors", func() {
+			Expect(FormatValue(errors.New("boom"))).To(Equal(`error("boom")`))
+		})
+
+		It("should show the name of functions", func() {
+			Expect(FormatValue(formattedHelper)).To(Equal("func github.com/cfmobile/gospy_test.formattedHelper"))
+		})
+
+		It("should abbreviate long lists", func() {
+			Expect(FormatValue([]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})).To(Equal("[1, 2, 3, 4, 5, 6, 7, 8, ... +2 more]"))
+		})
+
+		It("should truncate long values", func() {
+			formatted := FormatValue(strings.Repeat("a", 500))
+
+			Expect(len(formatted)).To(BeNumerically("<", 130))
+			Expect(formatted).To(HaveSuffix("..."))
+		})
+	})
+
+	Describe("GoSpy String()", func() {
+		var subject *GoSpy
+		var divide func(int, int) (int, error)
+
+		BeforeEach(func() {
+			divide = func(a int, b int) (int, error) {
+				if b == 0 {
+					panic("division by zero")
+				}
+				return a / b, nil
+			}
+			subject = Spy(&divide)
+		})
+
+		AfterEach(func() {
+			subject.Restore()
+		})
+
+		It("should show the target type when no calls were made", func() {
+			Expect(subject.String()).To(Equal("GoSpy on func(int, int) (int, error): 0 calls"))
+		})
+
+		It("should show a numbered log of calls with their returns and panics", func() {
+			divide(4, 2)
+			func() {
+				defer func() { recover() }()
+				divide(1, 0)
+			}()
+
+			Expect(fmt.Sprint(subject)).To(Equal("GoSpy on func(int, int) (int, error): 2 calls\n" +
+				"  #0 (4, 2) => (2, nil)\n" +
+				`  #1 (1, 0) panicked: "division by zero"`))
+		})
+
+		It("should be used for %v", func() {
+			Expect(fmt.Sprintf("%v", subject)).To(Equal(subject.String()))
+		})
+	})
+})
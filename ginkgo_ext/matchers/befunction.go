@@ -2,6 +2,7 @@ package matchers
 
 import (
 	"fmt"
+	"github.com/cfmobile/gospy"
 	"reflect"
 )
 
@@ -19,9 +20,9 @@ func (matcher *_BeFunctionMatcher) Match(actual interface{}) (success bool, err
 }
 
 func (matcher *_BeFunctionMatcher) FailureMessage(actual interface{}) (message string) {
-	return fmt.Sprintf("Expected\n\t%#v\nto be function\n\t%#v", actual, matcher.expected)
+	return fmt.Sprintf("Expected\n\t%s\nto be function\n\t%s", gospy.FormatValue(actual), gospy.FormatValue(matcher.expected))
 }
 
 func (matcher *_BeFunctionMatcher) NegatedFailureMessage(actual interface{}) (message string) {
-	return fmt.Sprintf("Expected\n\t%#v\nnot to be function\n\t%#v", actual, matcher.expected)
+	return fmt.Sprintf("Expected\n\t%s\nnot to be function\n\t%s", gospy.FormatValue(actual), gospy.FormatValue(matcher.expected))
 }
@@ -0,0 +1,21 @@
+package matchers
+
+import (
+	"fmt"
+	"github.com/cfmobile/gospy"
+	. "github.com/onsi/gomega"
+)
+
+// ContainElement(BeFunction(expected)), with failure messages rendered by gospy
+type _ContainFunctionMatcher struct {
+	OmegaMatcher
+	expected interface{}
+}
+
+func (matcher *_ContainFunctionMatcher) FailureMessage(actual interface{}) (message string) {
+	return fmt.Sprintf("Expected\n\t%s\nto contain function\n\t%s", gospy.FormatValue(actual), gospy.FormatValue(matcher.expected))
+}
+
+func (matcher *_ContainFunctionMatcher) NegatedFailureMessage(actual interface{}) (message string) {
+	return fmt.Sprintf("Expected\n\t%s\nnot to contain function\n\t%s", gospy.FormatValue(actual), gospy.FormatValue(matcher.expected))
+}
@@ -0,0 +1,31 @@
+package matchers_test
+
+import (
+	. "github.com/cfmobile/gospy/ginkgo_ext/matchers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func helper() {}
+
+func other() {}
+
+var _ = Describe("ContainFunction", func() {
+	It("should find the function among the elements of slices and arrays", func() {
+		Expect([]func(){other, helper}).To(ContainFunction(helper))
+		Expect([1]func(){other}).NotTo(ContainFunction(helper))
+	})
+
+	It("should find the function among the values of maps", func() {
+		Expect(map[string]func(){"helper": helper}).To(ContainFunction(helper))
+		Expect(map[string]func(){"other": other}).NotTo(ContainFunction(helper))
+	})
+
+	It("should render the functions in its failure messages", func() {
+		message := ContainFunction(helper).FailureMessage([]func(){other})
+
+		Expect(message).To(ContainSubstring("to contain function"))
+		Expect(message).To(ContainSubstring("matchers_test.helper"))
+		Expect(message).NotTo(ContainSubstring("0x"))
+	})
+})
@@ -0,0 +1,80 @@
+package matchers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cfmobile/gospy"
+	"strings"
+)
+
+// A call expected in a recorded sequence: to the named spy, with arguments matching
+type _RecordedStep struct {
+	spy      string
+	matchers []interface{}
+}
+
+type _HaveRecordedSequenceMatcher struct {
+	expected []_RecordedStep
+}
+
+func (matcher *_HaveRecordedSequenceMatcher) Match(actual interface{}) (success bool, err error) {
+	recorder, ok := actual.(*gospy.Recorder)
+	if !ok || recorder == nil {
+		return false, errors.New(fmt.Sprintf("HaveRecordedSequence matcher expects a *gospy.Recorder. Got:\n\t%s", gospy.FormatValue(actual)))
+	}
+
+	entries := recorder.Entries()
+	if len(entries) != len(matcher.expected) {
+		return false, nil
+	}
+
+	for i, step := range matcher.expected {
+		if entries[i].Spy != step.spy || !gospy.ArgsMatch(entries[i].Call.Args, step.matchers...) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func (matcher *_HaveRecordedSequenceMatcher) FailureMessage(actual interface{}) (message string) {
+	return fmt.Sprintf("Expected\n\t%s\nto have recorded the sequence\n\t%s", formatRecorder(actual), matcher.formatExpected())
+}
+
+func (matcher *_HaveRecordedSequenceMatcher) NegatedFailureMessage(actual interface{}) (message string) {
+	return fmt.Sprintf("Expected\n\t%s\nnot to have recorded the sequence\n\t%s", formatRecorder(actual), matcher.formatExpected())
+}
+
+func (matcher *_HaveRecordedSequenceMatcher) formatExpected() string {
+	steps := make([]string, len(matcher.expected))
+	for i, step := range matcher.expected {
+		steps[i] = step.spy + gospy.FormatArgs(step.matchers)
+	}
+
+	return strings.Join(steps, "\n\t")
+}
+
+func formatRecorder(actual interface{}) string {
+	if recorder, ok := actual.(*gospy.Recorder); ok && recorder != nil {
+		return strings.Replace(recorder.String(), "\n  ", "\n\t", -1)
+	}
+
+	return gospy.FormatValue(actual)
+}
+
+// Steps given as a spy name expect a call to it with any arguments
+func recordedSteps(expected []interface{}) []_RecordedStep {
+	steps := make([]_RecordedStep, len(expected))
+	for i, step := range expected {
+		switch step := step.(type) {
+		case _RecordedStep:
+			steps[i] = step
+		case string:
+			steps[i] = _RecordedStep{spy: step}
+		default:
+			panic(fmt.Sprintf("HaveRecordedSequence expects spy names or CallTo() steps. Got:\n\t%s", gospy.FormatValue(step)))
+		}
+	}
+
+	return steps
+}
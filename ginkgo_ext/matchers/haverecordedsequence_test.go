@@ -0,0 +1,54 @@
+package matchers_test
+
+import (
+	"errors"
+	. "github.com/cfmobile/gospy"
+	. "github.com/cfmobile/gospy/ginkgo_ext/matchers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HaveRecordedSequence", func() {
+	var connect func(string) error
+	var send func(string) (int, error)
+	var recorder *Recorder
+
+	BeforeEach(func() {
+		connect = func(string) error { return nil }
+		send = func(message string) (int, error) { return len(message), nil }
+
+		registry := NewRegistry()
+		recorder = NewRecorder().Attach(
+			registry.Spy(&connect).Named("connect"),
+			registry.SpyAndFakeWithReturn(&send, 0, errors.New("broken pipe")).Named("send"),
+		)
+
+		connect("db")
+		send("a")
+	})
+
+	It("should match the spy names of the sequence", func() {
+		Expect(recorder).To(HaveRecordedSequence("connect", "send"))
+		Expect(recorder).NotTo(HaveRecordedSequence("send", "connect"))
+		Expect(recorder).NotTo(HaveRecordedSequence("connect"))
+	})
+
+	It("should match the arguments of the steps", func() {
+		Expect(recorder).To(HaveRecordedSequence(CallTo("connect", "db"), CallTo("send", AnyArg())))
+		Expect(recorder).NotTo(HaveRecordedSequence(CallTo("connect", "cache"), "send"))
+	})
+
+	It("should show the timeline and the expected sequence on failure", func() {
+		matcher := HaveRecordedSequence("send", CallTo("connect", "db"))
+		Expect(matcher.Match(recorder)).To(BeFalse())
+
+		message := matcher.FailureMessage(recorder)
+		Expect(message).To(ContainSubstring(`connect("db") => (nil)`))
+		Expect(message).To(ContainSubstring("to have recorded the sequence\n\tsend()\n\tconnect(\"db\")"))
+	})
+
+	It("should refuse to match anything but a recorder", func() {
+		_, err := HaveRecordedSequence("connect").Match("recorder")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,40 @@
+package matchers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cfmobile/gospy"
+)
+
+type _MatchArgsMatcher struct {
+	expected gospy.ArgList
+}
+
+func (matcher *_MatchArgsMatcher) Match(actual interface{}) (success bool, err error) {
+	if actual == nil {
+		return false, errors.New("Refusing to compare <nil> to an ArgList")
+	}
+
+	args, ok := actual.(gospy.ArgList)
+	if !ok || len(args) != len(matcher.expected) {
+		return false, nil
+	}
+
+	return gospy.ArgsMatch(args, matcher.expected...), nil
+}
+
+func (matcher *_MatchArgsMatcher) FailureMessage(actual interface{}) (message string) {
+	return fmt.Sprintf("Expected args\n\t%s\nto match\n\t%s", formatActualArgs(actual), gospy.FormatArgs(matcher.expected))
+}
+
+func (matcher *_MatchArgsMatcher) NegatedFailureMessage(actual interface{}) (message string) {
+	return fmt.Sprintf("Expected args\n\t%s\nnot to match\n\t%s", formatActualArgs(actual), gospy.FormatArgs(matcher.expected))
+}
+
+func formatActualArgs(actual interface{}) string {
+	if args, ok := actual.(gospy.ArgList); ok {
+		return gospy.FormatArgs(args)
+	}
+
+	return gospy.FormatValue(actual)
+}
@@ -3,7 +3,6 @@ package matchers
 import (
 	"github.com/cfmobile/gospy"
 	. "github.com/onsi/gomega"
-	"github.com/onsi/gomega/matchers"
 	"github.com/onsi/gomega/types"
 )
 
@@ -12,9 +11,19 @@ func BeFunction(expected interface{}) OmegaMatcher {
 }
 
 var ContainFunction = func(expected interface{}) OmegaMatcher {
-	return ContainElement(BeFunction(expected))
+	return &_ContainFunctionMatcher{ContainElement(BeFunction(expected)), expected}
 }
 
 func MatchArgs(expected ...interface{}) types.GomegaMatcher {
-	return &matchers.EqualMatcher{Expected: gospy.ArgList(expected)}
+	return &_MatchArgsMatcher{gospy.ArgList(expected)}
+}
+
+// Matches a Recorder that logged exactly these calls, in order. Each step is a
+// spy name, or CallTo() to match the arguments too
+func HaveRecordedSequence(expected ...interface{}) types.GomegaMatcher {
+	return &_HaveRecordedSequenceMatcher{recordedSteps(expected)}
+}
+
+func CallTo(spyName string, matchers ...interface{}) interface{} {
+	return _RecordedStep{spy: spyName, matchers: matchers}
 }
@@ -0,0 +1,37 @@
+package ginkgo_ext
+
+import (
+	"fmt"
+	"github.com/cfmobile/gospy"
+	. "github.com/onsi/ginkgo"
+	"io"
+)
+
+// Makes the specs of the enclosing container write the call logs of the spies
+// in the registry to GinkgoWriter when they fail, and restores the spies each
+// spec left installed. Call it in the container, alongside BeforeEach
+func DumpSpiesOnFailure(registry *gospy.Registry) {
+	// Runs before every AfterEach, so the spies are still in the registry
+	JustAfterEach(func() {
+		if CurrentGinkgoTestDescription().Failed {
+			WriteSpyLogs(GinkgoWriter, registry)
+		}
+	})
+
+	AfterEach(func() {
+		registry.RestoreAll()
+	})
+}
+
+// Writes the call log of each spy in the registry, under its name
+func WriteSpyLogs(w io.Writer, registry *gospy.Registry) {
+	spies := registry.Spies()
+	if len(spies) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "Spy call logs:")
+	for _, spy := range spies {
+		fmt.Fprintf(w, "%s: %s\n", spy.Name(), spy)
+	}
+}
@@ -0,0 +1,75 @@
+package ginkgo_ext_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/cfmobile/gospy"
+	"github.com/cfmobile/gospy/ginkgo_ext"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+var _ = Describe("Spy logs on failure", func() {
+	It("should write the call log of every spy in the registry, under its name", func() {
+		registry := gospy.NewRegistry()
+		connect := func(string) error { return nil }
+		registry.SpyAndFakeWithReturn(&connect, errors.New("refused")).Named("connect")
+		connect("db")
+
+		var output bytes.Buffer
+		ginkgo_ext.WriteSpyLogs(&output, registry)
+
+		Expect(output.String()).To(Equal("Spy call logs:\n" +
+			"connect: GoSpy on func(string) error: 1 call\n" +
+			`  #0 ("db") => (error("refused"))` + "\n"))
+	})
+
+	It("should write nothing when the registry has no spies", func() {
+		var output bytes.Buffer
+		ginkgo_ext.WriteSpyLogs(&output, gospy.NewRegistry())
+
+		Expect(output.String()).To(BeEmpty())
+	})
+
+	Context("when a spec leaves spies installed", func() {
+		var registry = gospy.NewRegistry()
+		var upper = strings.ToUpper
+
+		// Runs after the AfterEach of the nested container, which restores the spies
+		AfterEach(func() {
+			Expect(registry.Spies()).To(BeEmpty())
+			Expect(upper("a")).To(Equal("A"))
+		})
+
+		Context("with spy logs dumped on failure", func() {
+			ginkgo_ext.DumpSpiesOnFailure(registry)
+
+			It("should restore them after the spec", func() {
+				registry.SpyAndFakeWithReturn(&upper, "faked")
+
+				Expect(upper("a")).To(Equal("faked"))
+			})
+		})
+	})
+
+	Context("when a spec fails", func() {
+		It("should write the call logs to GinkgoWriter, then restore the spies", func() {
+			path, err := gexec.Build("github.com/cfmobile/gospy/ginkgo_ext/testdata/failing")
+			Expect(err).NotTo(HaveOccurred())
+			defer gexec.CleanupBuildArtifacts()
+
+			session, err := gexec.Start(exec.Command(path), GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session, 10*time.Second).Should(gexec.Exit(1))
+			Expect(session.Out).To(gbytes.Say("Spy call logs:\n"))
+			Expect(session.Out).To(gbytes.Say(`connect: GoSpy on func\(string\) error: 1 call\n`))
+			Expect(session.Out).To(gbytes.Say(`  #0 \("db"\) => \(error\("refused"\)\)`))
+		})
+	})
+})
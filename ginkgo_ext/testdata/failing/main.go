@@ -0,0 +1,44 @@
+// Runs a spec failing with a spy in the registry, for the tests of DumpSpiesOnFailure
+package main
+
+import (
+	"errors"
+	"github.com/cfmobile/gospy"
+	"github.com/cfmobile/gospy/ginkgo_ext"
+	. "github.com/onsi/ginkgo"
+	"os"
+)
+
+type testingT struct {
+	failed bool
+}
+
+func (self *testingT) Fail() {
+	self.failed = true
+}
+
+func main() {
+	registry := gospy.NewRegistry()
+	connect := func(string) error { return nil }
+
+	Describe("A failing spec", func() {
+		ginkgo_ext.DumpSpiesOnFailure(registry)
+
+		It("should fail with a spy in the registry", func() {
+			registry.SpyAndFakeWithReturn(&connect, errors.New("refused")).Named("connect")
+			connect("db")
+
+			Fail("connected")
+		})
+	})
+
+	t := &testingT{}
+	RunSpecs(t, "Failing Suite")
+
+	if len(registry.Spies()) > 0 {
+		os.Exit(2)
+	}
+	if t.failed {
+		os.Exit(1)
+	}
+}
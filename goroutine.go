@@ -0,0 +1,21 @@
+package gospy
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+)
+
+// Parses the ID out of the "goroutine N [status]:" header of the current stack
+func goroutineID() int64 {
+	var buffer [64]byte
+	header := buffer[:runtime.Stack(buffer[:], false)]
+
+	fields := bytes.Fields(header)
+	if len(fields) < 2 {
+		return 0
+	}
+
+	id, _ := strconv.ParseInt(string(fields[1]), 10, 64)
+	return id
+}
@@ -3,8 +3,10 @@ package gospy
 import (
 	"errors"
 	"fmt"
-	"github.com/cfmobile/gmock"
 	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type ArgList []interface{}
@@ -12,14 +14,46 @@ type ArgList []interface{}
 type CallList []ArgList
 
 type GoSpy struct {
-	calls CallList
-	mock  *gmock.GMock
+	mutex        sync.Mutex
+	name         string
+	calls        CallList
+	records      []*callRecord
+	count        int
+	recording    recordingConfig
+	divergences  []Divergence
+	mock         *funcVar
+	behaviourFor func(record *callRecord) func(args []reflect.Value) []reflect.Value
+	fast         func() interface{}
+	wrapper      reflect.Value
+	installed    uintptr
+	listeners    []func(ArgList)
+	invocations  []argInvocation
+	spyReturns   bool
+	state        *CallState
+	recorders    []*Recorder
+	paused       bool
+	passThrough  bool
+}
+
+// Outcome of a call, kept alongside its entry in calls
+type callRecord struct {
+	index      int
+	seq        uint64
+	time       time.Time
+	recorded   bool
+	returns    []interface{}
+	returned   bool
+	panicValue interface{}
+	panicked   bool
+	origin     *CallOrigin
+	callbacks  []CallbackCall
+	children   map[int]*GoSpy
 }
 
 func Spy(targetFuncPtr interface{}) *GoSpy {
 	spy := createSpy(targetFuncPtr)
 	defaultFn := spy.getDefaultFn()
-	spy.setTargetFn(defaultFn)
+	spy.setTargetFn(defaultFn, spy.getDefaultFast())
 	return spy
 }
 
@@ -30,7 +64,14 @@ func SpyAndFake(targetFuncPtr interface{}) *GoSpy {
 func SpyAndFakeWithReturn(targetFuncPtr interface{}, fakeReturnValues ...interface{}) *GoSpy {
 	spy := createSpy(targetFuncPtr)
 	fakeReturnFn := spy.getFnWithReturnValues(fakeReturnValues)
-	spy.setTargetFn(fakeReturnFn)
+
+	// Return values computed from the arguments need them reflected
+	var fast func() interface{}
+	if !hasReturnBuilders(fakeReturnValues) {
+		fast = spy.getFastWithReturnValues(fakeReturnFn(nil))
+	}
+
+	spy.setTargetFn(fakeReturnFn, fast)
 	return spy
 }
 
@@ -42,7 +83,9 @@ func SpyAndFakeWithFunc(targetFuncPtr interface{}, mockFunc interface{}) *GoSpy
 	}
 
 	fakeFuncFn := spy.getFnWithMockFunc(mockFunc)
-	spy.setTargetFn(fakeFuncFn)
+	spy.setTargetFn(fakeFuncFn, func() interface{} {
+		return mockFunc
+	})
 	return spy
 }
 
@@ -51,51 +94,225 @@ func (self *GoSpy) Called() bool {
 }
 
 func (self *GoSpy) CallCount() int {
-	return len(self.calls)
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	return self.count
 }
 
+// The arguments of the calls kept, in the order they were made. Every call is
+// kept by default; under CountOnly(), KeepLast(), SampleEvery() or RecordOnly()
+// only those listed by CallIndices() are. The list is a copy
 func (self *GoSpy) Calls() CallList {
-	return self.calls
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	return append(CallList(nil), self.calls...)
 }
 
+// The arguments of the call at callIndex among every call made. Only the indices
+// listed by CallIndices() are available when SetRecording() drops calls, and others panic
 func (self *GoSpy) ArgsForCall(callIndex uint) ArgList {
-	return self.calls[callIndex]
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	position := self.mustPositionOf(int(callIndex))
+
+	return self.calls[position]
 }
 
 func (self *GoSpy) Reset() {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
 	self.calls = nil
+	self.records = nil
+	self.count = 0
+	self.divergences = nil
 }
 
 func (self *GoSpy) Restore() {
-	self.mock.Restore()
+	if err := self.uninstall(); err != nil {
+		panic(err.Error())
+	}
+}
+
+// fast, when not nil, returns a func of the target's type behaving as fn, so
+// common signatures can be called without going through reflection
+func (self *GoSpy) setTargetFn(fn func(args []reflect.Value) []reflect.Value, fast func() interface{}) {
+	self.setBehaviour(fn, fast)
+	self.install(self.newWrapper())
+}
+
+// A func of the target's type monitoring the calls made to it
+func (self *GoSpy) newWrapper() reflect.Value {
+	targetType := self.mock.target.Type()
+	if signature, ok := fastSignatures[targetType]; ok {
+		return reflect.ValueOf(signature.wrap(self))
+	}
+
+	wrapperFn := func(args []reflect.Value) (results []reflect.Value) {
+		record, behaviour, _ := self.startCall(func() ArgList {
+			return valuesToInterfaces(args)
+		})
+		defer self.finishCall(record, func() []interface{} {
+			return valuesToInterfaces(results)
+		})
+
+		return behaviour(args)
+	}
+
+	return reflect.MakeFunc(targetType, wrapperFn)
+}
+
+func (self *GoSpy) setBehaviour(behaviour func(args []reflect.Value) []reflect.Value, fast func() interface{}) {
+	self.setBehaviourFor(func(*callRecord) func(args []reflect.Value) []reflect.Value {
+		return behaviour
+	}, fast)
 }
 
-func (self *GoSpy) setTargetFn(fn func(args []reflect.Value) []reflect.Value) {
-	targetType := self.mock.GetTarget().Type()
-	wrapperFn := func(args []reflect.Value) []reflect.Value {
-		self.storeCall(args)
-		return reflect.MakeFunc(targetType, fn).Call(args)
+// For behaviours that depend on the call being made. The record is nil for calls made while paused
+func (self *GoSpy) setBehaviourFor(behaviourFor func(record *callRecord) func(args []reflect.Value) []reflect.Value, fast func() interface{}) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.behaviourFor, self.fast = behaviourFor, fast
+}
+
+// Counts the call and records its arguments, boxed by box, unless paused or
+// filtered out. Returns the record of the call (nil if paused) and what to run for it
+func (self *GoSpy) startCall(box func() ArgList) (*callRecord, func([]reflect.Value) []reflect.Value, func() interface{}) {
+	self.mutex.Lock()
+	paused, passThrough := self.paused, self.passThrough
+	behaviourFor, fast := self.behaviourFor, self.fast
+	recording := self.recording
+	invocations, spyReturns := self.invocations, self.spyReturns
+
+	var record *callRecord
+	if !paused && recording.callerFilter == nil {
+		record = self.countCall()
+	}
+	self.mutex.Unlock()
+
+	// The stack is walked outside the lock. Calls from other callers go to the original uncounted
+	if !paused && recording.callerFilter != nil {
+		if stackMatches(recording.callerFilter) {
+			self.mutex.Lock()
+			record = self.countCall()
+			self.mutex.Unlock()
+		} else {
+			passThrough = true
+		}
+	}
+
+	var behaviour func([]reflect.Value) []reflect.Value
+	if passThrough {
+		behaviour, fast = self.getDefaultFn(), self.getDefaultFast()
+	} else if behaviour = behaviourFor(record); len(invocations) > 0 {
+		behaviour, fast = self.withInvocations(record, invocations, behaviour), nil
+	}
+
+	if spyReturns {
+		behaviour, fast = self.withReturnedSpies(record, behaviour), nil
+	}
+
+	// Arguments are only boxed for calls that may be kept
+	if record == nil || !recording.mayRecord(record.index) {
+		return record, behaviour, fast
+	}
+
+	call := box()
+	if recording.predicate != nil && !recording.predicate(call) {
+		return record, behaviour, fast
 	}
 
-	targetFn := reflect.MakeFunc(targetType, wrapperFn)
-	self.mock.Replace(targetFn.Interface())
+	if recording.captureCallers {
+		record.origin = captureOrigin(recording.stackDepth)
+	}
+	record.time = time.Now()
+
+	self.mutex.Lock()
+	record.recorded = true
+	self.keep(record, call, recording.keepLast)
+	listeners, recorders := self.listeners, self.recorders
+	self.mutex.Unlock()
+
+	for _, recorder := range recorders {
+		recorder.add(self, record, call)
+	}
+
+	// Listeners are notified outside the lock, so they can query the spy
+	for _, listener := range listeners {
+		listener(call)
+	}
+
+	return record, behaviour, fast
+}
+
+// Must be called with the mutex held
+func (self *GoSpy) countCall() *callRecord {
+	record := &callRecord{index: self.count, seq: atomic.AddUint64(&callSequence, 1)}
+	self.count++
+	return record
 }
 
-func (self *GoSpy) storeCall(arguments []reflect.Value) {
-	var call ArgList
-	for _, arg := range arguments {
-		call = append(call, arg.Interface())
+// Deferred by the wrappers, so it can see the panic of the call if there is one
+func (self *GoSpy) finishCall(record *callRecord, results func() []interface{}) {
+	if record == nil || !record.recorded {
+		return
 	}
 
-	self.calls = append(self.calls, call)
+	if r := recover(); r != nil {
+		self.mutex.Lock()
+		record.panicValue, record.panicked = r, true
+		self.mutex.Unlock()
+		panic(r)
+	}
+
+	returns := results()
+
+	self.mutex.Lock()
+	record.returns, record.returned = returns, true
+	self.mutex.Unlock()
+}
+
+func (self *GoSpy) addListener(listener func(ArgList)) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.listeners = append(self.listeners, listener)
 }
 
 func (self *GoSpy) getDefaultFn() func(args []reflect.Value) []reflect.Value {
-	return self.mock.GetOriginal().Call
+	return func(args []reflect.Value) []reflect.Value {
+		return callWithArgs(self.outer(), args)
+	}
+}
+
+func (self *GoSpy) getDefaultFast() func() interface{} {
+	return func() interface{} {
+		return self.outer().Interface()
+	}
+}
+
+func (self *GoSpy) getFastWithReturnValues(results []reflect.Value) func() interface{} {
+	signature, ok := fastSignatures[self.mock.target.Type()]
+	if !ok {
+		return nil
+	}
+
+	fake := signature.constant(results)
+	return func() interface{} {
+		return fake
+	}
 }
 
 func (self *GoSpy) getFnWithReturnValues(fakeReturnValues []interface{}) func(args []reflect.Value) []reflect.Value {
-	targetType := self.mock.GetTarget().Type()
+	targetType := self.mock.target.Type()
+
+	if builder, ok := computedReturns(fakeReturnValues); ok {
+		return builder.computeFn(targetType)
+	}
 
 	// Gets the expected number of return values from the target
 	var numReturnValues = targetType.NumOut()
@@ -105,6 +322,7 @@ func (self *GoSpy) getFnWithReturnValues(fakeReturnValues []interface{}) func(ar
 	}
 
 	res := make([]reflect.Value, 0)
+	builders := make(map[int]*ReturnBuilder)
 
 	// Builds slice of return values, if required
 	for i := 0; i < numReturnValues; i++ {
@@ -113,20 +331,39 @@ func (self *GoSpy) getFnWithReturnValues(fakeReturnValues []interface{}) func(ar
 		var returnElem = returnItem.Elem()
 
 		// Gets value for return from fakeReturnValues, or leaves default constructed value if not available
-		if fakeReturnValues != nil && fakeReturnValues[i] != nil {
+		if builder, ok := returnBuilderAt(fakeReturnValues, i); ok {
+			if err := builder.isValidFor(targetType, i); err != nil {
+				panic(err.Error())
+			}
+			builders[i] = builder
+		} else if fakeReturnValues != nil && fakeReturnValues[i] != nil {
 			returnElem.Set(reflect.ValueOf(fakeReturnValues[i]))
 		}
 
 		res = append(res, returnElem)
 	}
 
-	return func([]reflect.Value) []reflect.Value {
-		return res
+	if len(builders) == 0 {
+		return func([]reflect.Value) []reflect.Value {
+			return res
+		}
+	}
+
+	return func(args []reflect.Value) []reflect.Value {
+		results := append([]reflect.Value(nil), res...)
+		for i, builder := range builders {
+			results[i] = builder.build(args, targetType.Out(i))
+		}
+
+		return results
 	}
 }
 
 func (self *GoSpy) getFnWithMockFunc(mockFunc interface{}) func(args []reflect.Value) []reflect.Value {
-	return reflect.ValueOf(mockFunc).Call
+	mockFuncValue := reflect.ValueOf(mockFunc)
+	return func(args []reflect.Value) []reflect.Value {
+		return callWithArgs(mockFuncValue, args)
+	}
 }
 
 func createSpy(targetFuncPtr interface{}) *GoSpy {
@@ -134,7 +371,7 @@ func createSpy(targetFuncPtr interface{}) *GoSpy {
 		panic(err.Error())
 	}
 
-	spy := &GoSpy{calls: nil, mock: gmock.CreateMockWithTarget(targetFuncPtr)}
+	spy := &GoSpy{calls: nil, mock: newFuncVar(targetFuncPtr)}
 
 	return spy
 }
@@ -169,3 +406,13 @@ func mockFuncIsValid(target interface{}, mockFunc interface{}) error {
 
 	return nil
 }
+
+// Calls fn with args as received by a reflect.MakeFunc implementation, where
+// variadic arguments are already packed into a slice
+func callWithArgs(fn reflect.Value, args []reflect.Value) []reflect.Value {
+	if fn.Type().IsVariadic() {
+		return fn.CallSlice(args)
+	}
+
+	return fn.Call(args)
+}
@@ -0,0 +1,44 @@
+package gospy
+
+import (
+	"runtime"
+	"strings"
+)
+
+// Names the spy, so it can be found in a registry and told apart in logs
+func (self *GoSpy) Named(name string) *GoSpy {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.name = name
+	return self
+}
+
+// The name given with Named(), or else the name of the function the target held
+// when the spy was created, such as "billing.Charge"
+func (self *GoSpy) Name() string {
+	self.mutex.Lock()
+	name := self.name
+	self.mutex.Unlock()
+
+	if name != "" {
+		return name
+	}
+
+	return self.derivedName()
+}
+
+func (self *GoSpy) derivedName() string {
+	original := self.mock.original
+	if original.IsNil() {
+		return original.Type().String()
+	}
+
+	fn := runtime.FuncForPC(original.Pointer())
+	if fn == nil {
+		return original.Type().String()
+	}
+
+	name := fn.Name()
+	return name[strings.LastIndex(name, "/")+1:]
+}
@@ -0,0 +1,39 @@
+//go:build linux
+
+#include "textflag.h"
+
+// func flushCode(addr uintptr, length int)
+//
+// Cleans the data cache lines holding the code to the point of unification,
+// then invalidates the instruction cache lines, so the new code gets fetched.
+// The line sizes come from CTR_EL0, which Linux lets user space read.
+TEXT ·flushCode(SB), NOSPLIT, $0-16
+	MOVD	addr+0(FP), R0
+	MOVD	length+8(FP), R1
+	ADD	R0, R1, R1
+	MRS	CTR_EL0, R2
+	MOVD	$4, R4
+	UBFX	$16, R2, $4, R3 // DminLine, log2 of the words in a data cache line
+	LSL	R3, R4, R3
+	AND	$15, R2, R5     // IminLine, log2 of the words in an instruction cache line
+	LSL	R5, R4, R5
+
+	SUB	$1, R3, R6
+	BIC	R6, R0, R7
+dcache:
+	DC	CVAU, R7
+	ADD	R3, R7, R7
+	CMP	R1, R7
+	BLO	dcache
+	DSB	$0xb // ISH
+
+	SUB	$1, R5, R6
+	BIC	R6, R0, R7
+icache:
+	WORD	$0xd50b7527 // IC IVAU, R7
+	ADD	R5, R7, R7
+	CMP	R1, R7
+	BLO	icache
+	DSB	$0xb // ISH
+	ISB	$0xf
+	RET
@@ -0,0 +1,67 @@
+//go:build linux && (amd64 || arm64)
+
+package patch
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+)
+
+const Supported = true
+
+// Fails instead of replacing existing mappings, where the kernel supports it
+const mapFixedNoReplace = 0x100000
+
+func rawMemory(addr uintptr, length int) []byte {
+	return unsafe.Slice(*(**byte)(unsafe.Pointer(&addr)), length)
+}
+
+func readCode(addr uintptr, length int) []byte {
+	return rawMemory(addr, length)
+}
+
+// Text pages are read-only, so they're made writable just for the duration of the copy
+func writeCode(addr uintptr, code []byte) error {
+	pageSize := uintptr(syscall.Getpagesize())
+	pageStart := addr &^ (pageSize - 1)
+	pages := rawMemory(pageStart, int(addr-pageStart)+len(code))
+
+	if err := syscall.Mprotect(pages, syscall.PROT_READ|syscall.PROT_WRITE|syscall.PROT_EXEC); err != nil {
+		return err
+	}
+
+	storeCode(addr, code)
+	flushCode(addr, len(code))
+
+	return syscall.Mprotect(pages, syscall.PROT_READ|syscall.PROT_EXEC)
+}
+
+// Maps executable memory within reach of direct jumps from addr, trying
+// addresses further and further away from it
+func allocateNear(addr uintptr, length int) (uintptr, error) {
+	const step, reach = 1 << 20, branchReach - pageSize
+	base := addr &^ (step - 1)
+
+	for distance := uintptr(step); distance < reach; distance += step {
+		for _, hint := range []uintptr{base - distance, base + distance} {
+			if hint < step || hint > base+reach {
+				continue // Below the lowest address that can be mapped, or wrapped around
+			}
+
+			mapped, _, errno := syscall.Syscall6(syscall.SYS_MMAP, hint, uintptr(length),
+				syscall.PROT_READ|syscall.PROT_EXEC, syscall.MAP_PRIVATE|syscall.MAP_ANONYMOUS|mapFixedNoReplace, ^uintptr(0), 0)
+			if errno != 0 {
+				continue
+			}
+
+			// Kernels without MAP_FIXED_NOREPLACE take the address as a hint, and may map elsewhere
+			if distance := int64(mapped) - int64(addr); distance > -reach && distance < reach {
+				return mapped, nil
+			}
+			syscall.Syscall(syscall.SYS_MUNMAP, mapped, uintptr(length), 0)
+		}
+	}
+
+	return 0, errors.New("no memory left within reach of the function for its trampoline")
+}
@@ -0,0 +1,25 @@
+//go:build !linux || !(amd64 || arm64)
+
+package patch
+
+import "errors"
+
+const Supported = false
+
+var errUnsupported = errors.New("Patching functions is not supported on this platform")
+
+type patchCode struct {
+	call uintptr
+}
+
+func preparePatch(entry uintptr, fnVarAddr uintptr, context uintptr) (*patchCode, error) {
+	return nil, errUnsupported
+}
+
+func (self *patchCode) apply() error {
+	return errUnsupported
+}
+
+func (self *patchCode) restore() error {
+	return errUnsupported
+}
@@ -0,0 +1,325 @@
+//go:build linux
+
+package patch
+
+import (
+	"errors"
+	"fmt"
+)
+
+type branchKind int
+
+const (
+	noBranch branchKind = iota
+	conditionalJump
+	jump
+	call
+	loop
+	ret
+	trap
+)
+
+// What relocating an x86-64 instruction needs to know about it: its length,
+// the relative branch it makes, and where its RIP-relative displacement is
+type instruction struct {
+	length    int
+	branch    branchKind
+	condition byte // Of conditional jumps, as in the low nibble of 0x7x
+	relOffset int  // Of the rel8 or rel32 of branches
+	relSize   int
+	ripOffset int // Of the disp32 of RIP-relative operands, or 0 if there is none
+	prefixed  bool
+}
+
+// Execution doesn't go on to the next instruction
+func (self instruction) terminates() bool {
+	return self.branch == jump || self.branch == ret || self.branch == trap
+}
+
+func (self instruction) target(addr uintptr, code []byte) uintptr {
+	next := addr + uintptr(self.length)
+	if self.relSize == 1 {
+		return next + uintptr(int8(code[self.relOffset]))
+	}
+
+	return next + uintptr(readInt32(code[self.relOffset:]))
+}
+
+func decodeInstruction(code []byte) (instruction, error) {
+	var decoded instruction
+	var operandSize16, rexW bool
+	i := 0
+
+	for ; i < len(code) && isLegacyPrefix(code[i]); i++ {
+		operandSize16 = operandSize16 || code[i] == 0x66
+		decoded.prefixed = true
+	}
+
+	if i < len(code) && code[i]&0xF0 == 0x40 {
+		rexW = code[i]&0x08 != 0
+		i++
+	}
+
+	if i >= len(code) {
+		return decoded, errTruncated
+	}
+
+	immediate := 4
+	if operandSize16 {
+		immediate = 2
+	}
+
+	op := code[i]
+	i++
+
+	var hasModRM bool
+	var immediateSize int
+	var err error
+
+	switch op {
+	case 0x0F:
+		if i >= len(code) {
+			return decoded, errTruncated
+		}
+		op2 := code[i]
+		i++
+
+		switch {
+		case op2 == 0x38:
+			i++
+			hasModRM = true
+		case op2 == 0x3A:
+			i++
+			hasModRM, immediateSize = true, 1
+		case op2 >= 0x80 && op2 <= 0x8F:
+			decoded.branch, decoded.condition = conditionalJump, op2&0x0F
+			decoded.relOffset, decoded.relSize = i, 4
+			immediateSize = 4
+		case op2 == 0x0B:
+			decoded.branch = trap
+		case op2 == 0x0F:
+			return decoded, unsupportedInstruction(code[:i])
+		default:
+			hasModRM = twoByteHasModRM(op2)
+			if twoByteHasImmediate8(op2) {
+				immediateSize = 1
+			}
+		}
+	case 0xC4, 0xC5:
+		hasModRM, immediateSize, i, err = decodeVEX(code, i-1)
+		if err != nil {
+			return decoded, err
+		}
+	default:
+		hasModRM, immediateSize, err = oneByteOperands(&decoded, op, immediate, rexW, code, i)
+		if err != nil {
+			return decoded, err
+		}
+		if decoded.relSize > 0 {
+			decoded.relOffset = i
+		}
+	}
+
+	if hasModRM {
+		length, ripRelative, err := modRMLength(code, i)
+		if err != nil {
+			return decoded, err
+		}
+		if ripRelative {
+			decoded.ripOffset = i + 1
+		}
+		i += length
+	}
+
+	decoded.length = i + immediateSize
+	if decoded.length > len(code) {
+		return decoded, errTruncated
+	}
+
+	return decoded, nil
+}
+
+func oneByteOperands(decoded *instruction, op byte, immediate int, rexW bool, code []byte, modRM int) (hasModRM bool, immediateSize int, err error) {
+	switch {
+	case op <= 0x3F && op&0x07 <= 3:
+		return true, 0, nil
+	case op <= 0x3F && op&0x07 == 4:
+		return false, 1, nil
+	case op <= 0x3F && op&0x07 == 5:
+		return false, immediate, nil
+	case op >= 0x50 && op <= 0x5F, op >= 0x6C && op <= 0x6F, op >= 0x90 && op <= 0x99, op >= 0x9B && op <= 0x9F,
+		op >= 0xA4 && op <= 0xA7, op >= 0xAA && op <= 0xAF, op >= 0xEC && op <= 0xEF,
+		op == 0xC9, op == 0xCF, op == 0xD7, op == 0xF1, op == 0xF4, op == 0xF5, op >= 0xF8 && op <= 0xFD:
+		return false, 0, nil
+	case op == 0x63, op >= 0x84 && op <= 0x8F, op >= 0xD0 && op <= 0xD3, op >= 0xD8 && op <= 0xDF:
+		return true, 0, nil
+	case op >= 0x70 && op <= 0x7F:
+		decoded.branch, decoded.condition, decoded.relSize = conditionalJump, op&0x0F, 1
+		return false, 1, nil
+	case op >= 0xE0 && op <= 0xE3:
+		decoded.branch, decoded.relSize = loop, 1
+		return false, 1, nil
+	case op == 0xE8:
+		decoded.branch, decoded.relSize = call, 4
+		return false, 4, nil
+	case op == 0xE9:
+		decoded.branch, decoded.relSize = jump, 4
+		return false, 4, nil
+	case op == 0xEB:
+		decoded.branch, decoded.relSize = jump, 1
+		return false, 1, nil
+	case op == 0xC3, op == 0xCB:
+		decoded.branch = ret
+		return false, 0, nil
+	case op == 0xC2, op == 0xCA:
+		decoded.branch = ret
+		return false, 2, nil
+	case op == 0xCC:
+		decoded.branch = trap
+		return false, 0, nil
+	case op == 0x6A, op == 0xA8, op >= 0xB0 && op <= 0xB7, op == 0xCD, op >= 0xE4 && op <= 0xE7:
+		return false, 1, nil
+	case op == 0x68, op == 0xA9:
+		return false, immediate, nil
+	case op >= 0xB8 && op <= 0xBF:
+		if rexW {
+			return false, 8, nil
+		}
+		return false, immediate, nil
+	case op >= 0xA0 && op <= 0xA3:
+		return false, 8, nil
+	case op == 0x6B, op == 0x80, op == 0x83, op == 0xC0, op == 0xC1, op == 0xC6:
+		return true, 1, nil
+	case op == 0x69, op == 0x81, op == 0xC7:
+		return true, immediate, nil
+	case op == 0xC8:
+		return false, 3, nil
+	case op == 0xF6, op == 0xF7:
+		if modRM >= len(code) {
+			return false, 0, errTruncated
+		}
+		if code[modRM]>>3&0x07 > 1 {
+			return true, 0, nil
+		}
+		if op == 0xF6 {
+			return true, 1, nil
+		}
+		return true, immediate, nil
+	case op == 0xFE, op == 0xFF:
+		if modRM >= len(code) {
+			return false, 0, errTruncated
+		}
+		switch code[modRM] >> 3 & 0x07 {
+		case 2, 3:
+			decoded.branch = call
+		case 4, 5:
+			decoded.branch = jump
+		}
+		return true, 0, nil
+	default:
+		return false, 0, unsupportedInstruction(code[:modRM])
+	}
+}
+
+// VEX prefixed instructions always have a ModRM byte, except VZEROUPPER and VZEROALL
+func decodeVEX(code []byte, start int) (hasModRM bool, immediateSize int, next int, err error) {
+	opcodeMap, i := byte(1), start+2
+	if code[start] == 0xC4 {
+		if start+1 >= len(code) {
+			return false, 0, 0, errTruncated
+		}
+		opcodeMap, i = code[start+1]&0x1F, start+3
+	}
+
+	if i >= len(code) {
+		return false, 0, 0, errTruncated
+	}
+	op := code[i]
+	i++
+
+	switch {
+	case opcodeMap == 1 && op == 0x77:
+		return false, 0, i, nil
+	case opcodeMap == 3, opcodeMap == 1 && twoByteHasImmediate8(op):
+		return true, 1, i, nil
+	case opcodeMap <= 3:
+		return true, 0, i, nil
+	default:
+		return false, 0, 0, unsupportedInstruction(code[start:i])
+	}
+}
+
+func modRMLength(code []byte, i int) (length int, ripRelative bool, err error) {
+	if i >= len(code) {
+		return 0, false, errTruncated
+	}
+
+	mod, rm := code[i]>>6, code[i]&0x07
+	length = 1
+
+	switch {
+	case mod == 3:
+		return length, false, nil
+	case rm == 4:
+		if i+1 >= len(code) {
+			return 0, false, errTruncated
+		}
+		length++
+		if mod == 0 && code[i+1]&0x07 == 5 {
+			length += 4
+		}
+	case mod == 0 && rm == 5:
+		return length + 4, true, nil
+	}
+
+	switch mod {
+	case 1:
+		length++
+	case 2:
+		length += 4
+	}
+
+	return length, false, nil
+}
+
+func isLegacyPrefix(b byte) bool {
+	switch b {
+	case 0x66, 0x67, 0xF0, 0xF2, 0xF3, 0x2E, 0x36, 0x3E, 0x26, 0x64, 0x65:
+		return true
+	}
+
+	return false
+}
+
+func twoByteHasModRM(op byte) bool {
+	switch {
+	case op >= 0x05 && op <= 0x09, op == 0x0E, op >= 0x30 && op <= 0x37, op == 0x77,
+		op >= 0xA0 && op <= 0xA2, op >= 0xA8 && op <= 0xAA, op >= 0xC8 && op <= 0xCF:
+		return false
+	}
+
+	return true
+}
+
+func twoByteHasImmediate8(op byte) bool {
+	switch op {
+	case 0x70, 0x71, 0x72, 0x73, 0xA4, 0xAC, 0xBA, 0xC2, 0xC4, 0xC5, 0xC6:
+		return true
+	}
+
+	return false
+}
+
+func readInt32(code []byte) int32 {
+	return int32(uint32(code[0]) | uint32(code[1])<<8 | uint32(code[2])<<16 | uint32(code[3])<<24)
+}
+
+func putInt32(code []byte, value int32) {
+	code[0], code[1], code[2], code[3] = byte(value), byte(value>>8), byte(value>>16), byte(value>>24)
+}
+
+var errTruncated = errors.New("truncated instruction")
+
+func unsupportedInstruction(code []byte) error {
+	return errors.New(fmt.Sprintf("unsupported instruction % x", code))
+}
@@ -0,0 +1,25 @@
+//go:build linux
+
+package patch
+
+// Loads the func var and jumps to the code of the func it holds, keeping the
+// closure context in DX as the Go ABI expects:
+//
+//	MOVQ $fnVarAddr, DX
+//	MOVQ (DX), DX
+//	JMP  (DX)
+func jumpCode(fnVarAddr uintptr) []byte {
+	return []byte{
+		0x48, 0xBA,
+		byte(fnVarAddr),
+		byte(fnVarAddr >> 8),
+		byte(fnVarAddr >> 16),
+		byte(fnVarAddr >> 24),
+		byte(fnVarAddr >> 32),
+		byte(fnVarAddr >> 40),
+		byte(fnVarAddr >> 48),
+		byte(fnVarAddr >> 56),
+		0x48, 0x8B, 0x12,
+		0xFF, 0x22,
+	}
+}
@@ -0,0 +1,26 @@
+//go:build linux
+
+package patch
+
+// Loads the func var and jumps to the code of the func it holds, keeping the
+// closure context in R26 as the Go ABI expects:
+//
+//	MOVZ R26, fnVarAddr[0:16]
+//	MOVK R26, fnVarAddr[16:32] << 16
+//	MOVK R26, fnVarAddr[32:48] << 32
+//	MOVK R26, fnVarAddr[48:64] << 48
+//	LDR  R26, [R26]
+//	LDR  R27, [R26]
+//	BR   R27
+func jumpCode(fnVarAddr uintptr) []byte {
+	const ctxt, tmp = 26, 27
+
+	instructions := moveWide(ctxt, fnVarAddr)
+	instructions = append(instructions,
+		0xF9400000|ctxt<<5|ctxt,
+		0xF9400000|ctxt<<5|tmp,
+		0xD61F0000|tmp<<5,
+	)
+
+	return encode(instructions...)
+}
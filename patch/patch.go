@@ -0,0 +1,178 @@
+package patch
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cfmobile/gospy"
+	"reflect"
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+// Entries with a patch applied. Patching one again would build a trampoline
+// over the first patch's jump, which restoring out of order breaks
+var patchedEntries = struct {
+	sync.Mutex
+	entries map[uintptr]bool
+}{entries: make(map[uintptr]bool)}
+
+type Patch struct {
+	*gospy.GoSpy
+
+	mutex    sync.Mutex
+	target   reflect.Value
+	entry    uintptr
+	code     *patchCode
+	original reflect.Value
+	fnVar    reflect.Value
+	patched  bool
+}
+
+func Spy(targetFunc interface{}) *Patch {
+	return createPatch(targetFunc, gospy.Spy)
+}
+
+func SpyAndFake(targetFunc interface{}) *Patch {
+	return createPatch(targetFunc, gospy.SpyAndFake)
+}
+
+func SpyAndFakeWithReturn(targetFunc interface{}, fakeReturnValues ...interface{}) *Patch {
+	return createPatch(targetFunc, func(fnPtr interface{}) *gospy.GoSpy {
+		return gospy.SpyAndFakeWithReturn(fnPtr, fakeReturnValues...)
+	})
+}
+
+func SpyAndFakeWithFunc(targetFunc interface{}, mockFunc interface{}) *Patch {
+	return createPatch(targetFunc, func(fnPtr interface{}) *gospy.GoSpy {
+		return gospy.SpyAndFakeWithFunc(fnPtr, mockFunc)
+	})
+}
+
+func (self *Patch) Restore() {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	if self.patched {
+		if err := self.code.restore(); err != nil {
+			panic(fmt.Sprintf("Failed to restore the original code of %s: %s", self.name(), err.Error()))
+		}
+		self.patched = false
+		releaseEntry(self.entry)
+	}
+
+	self.GoSpy.Restore()
+}
+
+func createPatch(targetFunc interface{}, spyConstructor func(fnPtr interface{}) *gospy.GoSpy) *Patch {
+	if err := targetIsValid(targetFunc); err != nil {
+		panic(err.Error())
+	}
+
+	target := reflect.ValueOf(targetFunc)
+	patch := &Patch{target: target, entry: target.Pointer()}
+
+	if !claimEntry(patch.entry) {
+		panic(fmt.Sprintf("%s is already patched. Restore its patch before patching it again", patch.name()))
+	}
+	defer func() {
+		if !patch.patched {
+			releaseEntry(patch.entry)
+		}
+	}()
+
+	// The func var starts out calling the original code, and is what the patched code jumps to
+	patch.fnVar = reflect.New(target.Type())
+	patch.fnVar.Elem().Set(reflect.MakeFunc(target.Type(), patch.callOriginal))
+
+	code, err := preparePatch(patch.entry, patch.fnVar.Pointer(), closureOf(target))
+	if err != nil {
+		panic(fmt.Sprintf("Failed to patch %s: %s", patch.name(), err.Error()))
+	}
+	patch.code = code
+	patch.original = funcAt(target.Type(), code.call)
+
+	patch.GoSpy = spyConstructor(patch.fnVar.Interface())
+
+	if err := code.apply(); err != nil {
+		patch.GoSpy.Restore()
+		panic(fmt.Sprintf("Failed to patch %s: %s", patch.name(), err.Error()))
+	}
+	patch.patched = true
+
+	return patch
+}
+
+func claimEntry(entry uintptr) bool {
+	patchedEntries.Lock()
+	defer patchedEntries.Unlock()
+
+	if patchedEntries.entries[entry] {
+		return false
+	}
+
+	patchedEntries.entries[entry] = true
+	return true
+}
+
+func releaseEntry(entry uintptr) {
+	patchedEntries.Lock()
+	defer patchedEntries.Unlock()
+
+	delete(patchedEntries.entries, entry)
+}
+
+// Runs the original code through the trampoline, leaving the patch in place
+func (self *Patch) callOriginal(args []reflect.Value) []reflect.Value {
+	if self.target.Type().IsVariadic() {
+		return self.original.CallSlice(args)
+	}
+
+	return self.original.Call(args)
+}
+
+func (self *Patch) name() string {
+	if fn := runtime.FuncForPC(self.entry); fn != nil {
+		return fn.Name()
+	}
+
+	return fmt.Sprintf("%+v", self.target.Type())
+}
+
+// The closure the func value points to, holding the code pointer then any captured variables
+func closureOf(fn reflect.Value) uintptr {
+	holder := reflect.New(fn.Type())
+	holder.Elem().Set(fn)
+
+	return *(*uintptr)(holder.UnsafePointer())
+}
+
+// A func of the given type running the code at addr
+func funcAt(fnType reflect.Type, addr uintptr) reflect.Value {
+	closure := &addr
+	fn := reflect.New(fnType)
+	*(*unsafe.Pointer)(fn.UnsafePointer()) = unsafe.Pointer(closure)
+
+	return fn.Elem()
+}
+
+func targetIsValid(target interface{}) error {
+	if !Supported {
+		return errors.New(fmt.Sprintf("Patching functions is not supported on %s/%s", runtime.GOOS, runtime.GOARCH))
+	}
+
+	if target == nil {
+		return errors.New("Target function can't be nil")
+	}
+
+	targetValue := reflect.ValueOf(target)
+	if targetValue.Kind() != reflect.Func {
+		return errors.New(fmt.Sprintf("Patch target has to be a function [type: %+v]", targetValue.Kind()))
+	}
+
+	if targetValue.IsNil() {
+		return errors.New("Target function can't be nil")
+	}
+
+	return nil
+}
@@ -0,0 +1,221 @@
+//go:build linux && (amd64 || arm64)
+
+package patch_test
+
+import (
+	"github.com/cfmobile/gospy"
+	. "github.com/cfmobile/gospy/patch"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+//go:noinline
+func double(i int) int {
+	return i * 2
+}
+
+// Has a frame larger than the stack of a new goroutine, so calling it grows the stack
+//
+//go:noinline
+func sumOfDigits(digits string) int {
+	var buffer [16384]byte
+	n := copy(buffer[:], digits)
+
+	sum := 0
+	for _, digit := range buffer[:n] {
+		sum += int(digit - '0')
+	}
+	return sum
+}
+
+//go:noinline
+func join(separator string, parts ...string) string {
+	return strings.Join(parts, separator)
+}
+
+type counter struct {
+	count int
+}
+
+//go:noinline
+func (self *counter) Add(i int) int {
+	self.count += i
+	return self.count
+}
+
+var _ = Describe("Patch", func() {
+	var subject *Patch
+	var panicked bool
+
+	BeforeEach(func() {
+		subject = nil
+		panicked = false
+	})
+
+	AfterEach(func() {
+		if subject != nil {
+			subject.Restore()
+		}
+	})
+
+	panicRecover := func() {
+		panicked = recover() != nil
+	}
+
+	Context("when calling Spy() on a package function", func() {
+		BeforeEach(func() {
+			subject = Spy(double)
+		})
+
+		It("should not have affected the function's behaviour", func() {
+			Expect(double(2)).To(Equal(4))
+		})
+
+		It("should record the calls made directly to the function", func() {
+			double(1)
+			double(3)
+
+			Expect(subject.CallCount()).To(Equal(2))
+			Expect(subject.Calls()).To(Equal(gospy.CallList{{1}, {3}}))
+		})
+
+		Context("when Restore() is called", func() {
+			BeforeEach(func() {
+				subject.Restore()
+			})
+
+			It("should no longer monitor calls to the function", func() {
+				Expect(double(5)).To(Equal(10))
+				Expect(subject.Called()).To(BeFalse())
+			})
+		})
+	})
+
+	Context("when the spied function is called concurrently", func() {
+		const goroutines, calls = 16, 2000
+
+		BeforeEach(func() {
+			subject = Spy(double)
+		})
+
+		It("should record every call and answer each one with the original", func() {
+			var wait sync.WaitGroup
+			var wrong int64
+			for g := 0; g < goroutines; g++ {
+				wait.Add(1)
+				go func(g int) {
+					defer wait.Done()
+					for i := 0; i < calls; i++ {
+						if double(g*calls+i) != 2*(g*calls+i) {
+							atomic.AddInt64(&wrong, 1)
+						}
+					}
+				}(g)
+			}
+			wait.Wait()
+
+			Expect(wrong).To(BeZero())
+			Expect(subject.CallCount()).To(Equal(goroutines * calls))
+		})
+	})
+
+	Context("when the original has to grow the stack", func() {
+		BeforeEach(func() {
+			subject = Spy(sumOfDigits)
+		})
+
+		It("should record the call once and answer with the original", func() {
+			result := make(chan int)
+			go func() {
+				result <- sumOfDigits("1234")
+			}()
+
+			Expect(<-result).To(Equal(10))
+			Expect(subject.CallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when calling Spy() on a variadic function", func() {
+		BeforeEach(func() {
+			subject = Spy(join)
+		})
+
+		It("should pass the variadic arguments through to the original", func() {
+			Expect(join("-", "a", "b")).To(Equal("a-b"))
+			Expect(subject.Calls()).To(Equal(gospy.CallList{{"-", []string{"a", "b"}}}))
+		})
+	})
+
+	Context("when calling SpyAndFakeWithReturn() on a package function", func() {
+		BeforeEach(func() {
+			subject = SpyAndFakeWithReturn(double, 42)
+		})
+
+		It("should return the fake value", func() {
+			Expect(double(2)).To(Equal(42))
+			Expect(subject.ArgsForCall(0)).To(Equal(gospy.ArgList{2}))
+		})
+
+		It("should put the original code back on Restore()", func() {
+			subject.Restore()
+
+			Expect(double(2)).To(Equal(4))
+		})
+	})
+
+	Context("when calling SpyAndFakeWithFunc() on a method expression", func() {
+		BeforeEach(func() {
+			subject = SpyAndFakeWithFunc((*counter).Add, func(c *counter, i int) int {
+				return -i
+			})
+		})
+
+		It("should replace the method's behaviour", func() {
+			c := &counter{}
+
+			Expect(c.Add(3)).To(Equal(-3))
+			Expect(c.count).To(BeZero())
+			Expect(subject.CallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when the function is already patched", func() {
+		BeforeEach(func() {
+			subject = Spy(double)
+		})
+
+		It("should refuse to patch it again", func() {
+			func() {
+				defer panicRecover()
+				SpyAndFakeWithReturn(double, 7)
+			}()
+
+			Expect(panicked).To(BeTrue())
+			Expect(double(2)).To(Equal(4))
+			Expect(subject.CallCount()).To(Equal(1))
+		})
+
+		It("should patch it again once restored", func() {
+			subject.Restore()
+			subject = SpyAndFakeWithReturn(double, 7)
+
+			Expect(double(2)).To(Equal(7))
+			subject.Restore()
+			Expect(double(2)).To(Equal(4))
+		})
+	})
+
+	Context("when calling Spy() with something other than a function", func() {
+		BeforeEach(func() {
+			defer panicRecover()
+			subject = Spy("not a function")
+		})
+
+		It("should have panicked", func() {
+			Expect(panicked).To(BeTrue())
+		})
+	})
+})
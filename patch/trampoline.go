@@ -0,0 +1,122 @@
+//go:build linux && (amd64 || arm64)
+
+package patch
+
+const (
+	prologueSize = 64
+	tailSize     = 256
+	pageSize     = 4096
+)
+
+// The code written over a function to patch it, and what it replaces.
+//
+// The entry jumps to the spy. Calls to the original go through the trampoline,
+// a copy of the first instructions of the function moved to a page of their
+// own, which then jumps back to the rest of the function. The function's code
+// is never modified while it is being called through.
+//
+// When the stack check of the prologue fails, the function grows the stack
+// then starts over by jumping to its entry. That jump is redirected to a
+// second jump written after the first, back into the trampoline, so growing
+// the stack doesn't go through the spy again.
+type patchCode struct {
+	entry    uintptr
+	original []byte
+	jump     []byte
+
+	tail         uintptr // The jump back to the entry after growing the stack, or 0
+	tailOriginal []byte
+	tailJump     []byte
+
+	// Called with the closure context of the target, then goes on into the moved instructions
+	call uintptr
+}
+
+// Trampolines are never unmapped, as a call may still be running through one after Restore()
+func preparePatch(entry uintptr, fnVarAddr uintptr, context uintptr) (*patchCode, error) {
+	prologue := readCode(entry, prologueSize)
+
+	stackCheck, err := findStackCheck(entry, prologue)
+	if err != nil {
+		return nil, err
+	}
+
+	patchLength := jumpLength
+	if stackCheck != 0 {
+		patchLength += jumpLength
+	}
+
+	page, err := allocateNear(entry, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// The spy's jump, then the call entry setting the closure context, then the moved instructions
+	code := jumpCode(fnVarAddr)
+	call := page + uintptr(len(code))
+	code = append(code, contextCode(context)...)
+	trampoline := page + uintptr(len(code))
+
+	relocated, err := relocate(entry, prologue, patchLength, trampoline)
+	if err != nil {
+		return nil, err
+	}
+	code = append(code, relocated...)
+
+	if err := writeCode(page, code); err != nil {
+		return nil, err
+	}
+
+	patch := &patchCode{entry: entry, call: call}
+	patch.original = append([]byte(nil), prologue[:patchLength]...)
+	patch.jump, err = jumpTo(entry, page)
+	if err != nil {
+		return nil, err
+	}
+
+	if stackCheck != 0 {
+		retry, err := jumpTo(entry+jumpLength, trampoline)
+		if err != nil {
+			return nil, err
+		}
+		patch.jump = append(patch.jump, retry...)
+
+		patch.tail, patch.tailOriginal, patch.tailJump, err = retargetTail(entry, stackCheck)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return patch, nil
+}
+
+// The second jump goes in first: a call already past the entry then goes
+// through the trampoline, running the moved instructions again
+func (self *patchCode) apply() error {
+	if len(self.jump) > jumpLength {
+		if err := writeCode(self.entry+jumpLength, self.jump[jumpLength:]); err != nil {
+			return err
+		}
+	}
+
+	if err := writeCode(self.entry, self.jump[:jumpLength]); err != nil {
+		return err
+	}
+
+	if self.tail != 0 {
+		return writeCode(self.tail, self.tailJump)
+	}
+
+	return nil
+}
+
+// The tail goes back to the entry first, so no call starts over in the middle of restored code
+func (self *patchCode) restore() error {
+	if self.tail != 0 {
+		if err := writeCode(self.tail, self.tailOriginal); err != nil {
+			return err
+		}
+	}
+
+	return writeCode(self.entry, self.original)
+}
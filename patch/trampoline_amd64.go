@@ -0,0 +1,217 @@
+//go:build linux
+
+package patch
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	jumpLength  = 5       // JMP rel32
+	branchReach = 1 << 30 // Well within reach of rel32
+)
+
+// Where the stack check of the prologue jumps to when the stack has to grow, or 0
+// if the function doesn't check the stack, such as leaf functions
+func findStackCheck(entry uintptr, prologue []byte) (uintptr, error) {
+	offset := 0
+
+	for i := 0; i < 4; i++ {
+		decoded, err := decodeInstruction(prologue[offset:])
+		if err != nil {
+			return 0, err
+		}
+
+		// JBE to the end of the function, following CMPQ SP, 16(R14). Large
+		// frames are checked for wrapping around first, with a JB to the same place
+		if decoded.branch == conditionalJump && (decoded.condition == 0x2 || decoded.condition == 0x6) {
+			target := decoded.target(entry+uintptr(offset), prologue[offset:])
+			if target > entry {
+				return target, nil
+			}
+		}
+
+		if decoded.terminates() {
+			break
+		}
+		offset += decoded.length
+	}
+
+	return 0, nil
+}
+
+// The instructions covering the first length bytes, which get overwritten.
+// Bytes past the last instruction of a short function must be padding
+func instructionsToMove(prologue []byte, length int) ([]instruction, error) {
+	var moved []instruction
+	offset := 0
+
+	for offset < length {
+		decoded, err := decodeInstruction(prologue[offset:])
+		if err != nil {
+			return nil, err
+		}
+
+		moved = append(moved, decoded)
+		offset += decoded.length
+
+		if decoded.terminates() {
+			for ; offset < length; offset++ {
+				if prologue[offset] != 0xCC {
+					return nil, errors.New("function too short to be patched")
+				}
+			}
+		}
+	}
+
+	return moved, nil
+}
+
+// Copies the instructions covering the first length bytes to addr, adjusting
+// their relative addresses. Short jumps become near ones, and a jump to what
+// follows them is added
+func relocate(entry uintptr, prologue []byte, length int, addr uintptr) ([]byte, error) {
+	moved, err := instructionsToMove(prologue, length)
+	if err != nil {
+		return nil, err
+	}
+
+	var code []byte
+	offset := 0
+	movedLength := 0
+	for _, decoded := range moved {
+		movedLength += decoded.length
+	}
+
+	for _, decoded := range moved {
+		source := prologue[offset : offset+decoded.length]
+		from := entry + uintptr(offset)
+		to := addr + uintptr(len(code))
+		offset += decoded.length
+
+		switch {
+		case decoded.branch == call, decoded.branch == loop:
+			// Returning into the trampoline would leave the runtime unable to walk the stack
+			return nil, errors.New(fmt.Sprintf("can't move instruction % x out of the function", source))
+		case decoded.relSize > 0:
+			target := decoded.target(from, source)
+			if target >= entry && target < entry+uintptr(movedLength) {
+				return nil, errors.New(fmt.Sprintf("can't move jump % x within the moved instructions", source))
+			}
+			if decoded.prefixed {
+				return nil, unsupportedInstruction(source)
+			}
+
+			var branch []byte
+			var err error
+			if decoded.branch == jump {
+				branch, err = jumpTo(to, target)
+			} else {
+				branch, err = conditionalJumpTo(to, target, decoded.condition)
+			}
+			if err != nil {
+				return nil, err
+			}
+			code = append(code, branch...)
+		case decoded.ripOffset > 0:
+			instruction := append([]byte(nil), source...)
+			displacement := int64(readInt32(source[decoded.ripOffset:])) + int64(from) - int64(to)
+			if displacement != int64(int32(displacement)) {
+				return nil, errors.New("trampoline out of reach of RIP-relative operand")
+			}
+			putInt32(instruction[decoded.ripOffset:], int32(displacement))
+			code = append(code, instruction...)
+		default:
+			code = append(code, source...)
+		}
+
+		if decoded.terminates() {
+			return code, nil
+		}
+	}
+
+	back, err := jumpTo(addr+uintptr(len(code)), entry+uintptr(movedLength))
+	if err != nil {
+		return nil, err
+	}
+
+	return append(code, back...), nil
+}
+
+// Finds the jump back to the entry at the end of the code growing the stack,
+// and makes it jump to the second jump of the patch instead
+func retargetTail(entry uintptr, stackCheck uintptr) (tail uintptr, original []byte, retargeted []byte, err error) {
+	code := readCode(stackCheck, tailSize)
+	offset := 0
+
+	for offset < len(code)-16 {
+		decoded, err := decodeInstruction(code[offset:])
+		if err != nil {
+			return 0, nil, nil, err
+		}
+
+		if decoded.branch == jump && decoded.relSize > 0 {
+			source := code[offset : offset+decoded.length]
+			addr := stackCheck + uintptr(offset)
+			if decoded.target(addr, source) != entry {
+				break
+			}
+
+			original = append([]byte(nil), source...)
+			retargeted = append([]byte(nil), source...)
+
+			// The jump goes backwards, so it stays in reach when moved forward
+			if decoded.relSize == 1 {
+				retargeted[decoded.relOffset] = byte(int8(source[decoded.relOffset]) + jumpLength)
+			} else {
+				putInt32(retargeted[decoded.relOffset:], readInt32(source[decoded.relOffset:])+jumpLength)
+			}
+			return addr, original, retargeted, nil
+		}
+
+		if decoded.terminates() {
+			break
+		}
+		offset += decoded.length
+	}
+
+	return 0, nil, nil, errors.New("can't find where the function starts over after growing the stack")
+}
+
+func jumpTo(from uintptr, to uintptr) ([]byte, error) {
+	code := []byte{0xE9, 0, 0, 0, 0}
+	return code, putRel32(code[1:], from+uintptr(len(code)), to)
+}
+
+func conditionalJumpTo(from uintptr, to uintptr, condition byte) ([]byte, error) {
+	code := []byte{0x0F, 0x80 | condition, 0, 0, 0, 0}
+	return code, putRel32(code[2:], from+uintptr(len(code)), to)
+}
+
+func putRel32(code []byte, next uintptr, to uintptr) error {
+	rel := int64(to) - int64(next)
+	if rel != int64(int32(rel)) {
+		return errors.New("trampoline out of reach of the function")
+	}
+
+	putInt32(code, int32(rel))
+	return nil
+}
+
+// MOVQ $context, DX
+func contextCode(context uintptr) []byte {
+	code := []byte{0x48, 0xBA}
+	for i := 0; i < 8; i++ {
+		code = append(code, byte(context>>(8*i)))
+	}
+
+	return code
+}
+
+func storeCode(addr uintptr, code []byte) {
+	copy(rawMemory(addr, len(code)), code)
+}
+
+// x86 keeps the instruction cache coherent with writes to code
+func flushCode(addr uintptr, length int) {}
@@ -0,0 +1,257 @@
+//go:build linux
+
+package patch
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"unsafe"
+)
+
+const (
+	jumpLength  = 4       // B imm26
+	branchReach = 1 << 27 // Of B, in bytes
+)
+
+const (
+	conditionLO = 0x3
+	conditionLS = 0x9
+)
+
+// Where the stack check of the prologue jumps to when the stack has to grow, or 0
+// if the function doesn't check the stack, such as leaf functions
+func findStackCheck(entry uintptr, prologue []byte) (uintptr, error) {
+	for offset := 0; offset < 6*4; offset += 4 {
+		word := binary.LittleEndian.Uint32(prologue[offset:])
+
+		// B.LS to the end of the function, following CMP R16, RSP. Large
+		// frames are checked for wrapping around first, with a B.LO to the same place
+		if isConditionalBranch(word) && (word&0xF == conditionLS || word&0xF == conditionLO) {
+			if target := branchTarget(entry+uintptr(offset), word); target > entry {
+				return target, nil
+			}
+		}
+
+		if terminates(word) {
+			break
+		}
+	}
+
+	return 0, nil
+}
+
+// Copies the instructions covering the first length bytes to addr, adjusting
+// their relative addresses, and adds a jump to what follows them. Conditional
+// branches go through a B, which reaches further
+func relocate(entry uintptr, prologue []byte, length int, addr uintptr) ([]byte, error) {
+	var instructions []uint32
+
+	for offset := 0; offset < length; offset += 4 {
+		word := binary.LittleEndian.Uint32(prologue[offset:])
+		from := entry + uintptr(offset)
+		to := addr + uintptr(4*len(instructions))
+
+		switch {
+		case isCall(word):
+			// Returning into the trampoline would leave the runtime unable to walk the stack
+			return nil, errors.New(fmt.Sprintf("can't move instruction %08x out of the function", word))
+		case isLiteralLoad(word):
+			return nil, errors.New(fmt.Sprintf("unsupported instruction %08x", word))
+		case isBranch(word) || isConditionalBranch(word) || isCompareBranch(word) || isTestBranch(word):
+			target := branchTarget(from, word)
+			if target >= entry && target < entry+uintptr(length) {
+				return nil, errors.New(fmt.Sprintf("can't move branch %08x within the moved instructions", word))
+			}
+
+			if isBranch(word) {
+				branch, err := branchTo(to, target)
+				if err != nil {
+					return nil, err
+				}
+				instructions = append(instructions, branch)
+				break
+			}
+
+			// The condition skips over a B past the B to the target
+			skip := uint32(0x14000002)
+			branch, err := branchTo(to+8, target)
+			if err != nil {
+				return nil, err
+			}
+			instructions = append(instructions, withOffset(word, 8), skip, branch)
+		case isAddressOf(word):
+			instructions = append(instructions, moveWide(word&0x1F, addressOf(from, word))...)
+		default:
+			instructions = append(instructions, word)
+		}
+
+		if terminates(word) {
+			// Whatever the patch covers past the end of the function must be padding
+			for offset += 4; offset < length; offset += 4 {
+				if binary.LittleEndian.Uint32(prologue[offset:]) != 0 {
+					return nil, errors.New("function too short to be patched")
+				}
+			}
+			return encode(instructions...), nil
+		}
+	}
+
+	back, err := branchTo(addr+uintptr(4*len(instructions)), entry+uintptr(length))
+	if err != nil {
+		return nil, err
+	}
+
+	return encode(append(instructions, back)...), nil
+}
+
+// Finds the branch back to the entry at the end of the code growing the stack,
+// and makes it branch to the second jump of the patch instead
+func retargetTail(entry uintptr, stackCheck uintptr) (tail uintptr, original []byte, retargeted []byte, err error) {
+	code := readCode(stackCheck, tailSize)
+
+	for offset := 0; offset < len(code); offset += 4 {
+		word := binary.LittleEndian.Uint32(code[offset:])
+		addr := stackCheck + uintptr(offset)
+
+		if isBranch(word) {
+			if branchTarget(addr, word) != entry {
+				break
+			}
+
+			// The branch goes backwards, so it stays in reach when moved forward
+			return addr, encode(word), encode(word&^0x3FFFFFF | (word+jumpLength/4)&0x3FFFFFF), nil
+		}
+
+		if terminates(word) {
+			break
+		}
+	}
+
+	return 0, nil, nil, errors.New("can't find where the function starts over after growing the stack")
+}
+
+func jumpTo(from uintptr, to uintptr) ([]byte, error) {
+	branch, err := branchTo(from, to)
+	return encode(branch), err
+}
+
+func branchTo(from uintptr, to uintptr) (uint32, error) {
+	offset := int64(to) - int64(from)
+	if offset < -branchReach || offset >= branchReach {
+		return 0, errors.New("trampoline out of reach of the function")
+	}
+
+	return 0x14000000 | uint32(offset>>2)&0x3FFFFFF, nil
+}
+
+// MOVZ/MOVK R26, context
+func contextCode(context uintptr) []byte {
+	return encode(moveWide(26, context)...)
+}
+
+// MOVZ then MOVKs setting the register to value, 16 bits at a time
+func moveWide(register uint32, value uintptr) []uint32 {
+	return []uint32{
+		0xD2800000 | uint32(value&0xFFFF)<<5 | register,
+		0xF2A00000 | uint32(value>>16&0xFFFF)<<5 | register,
+		0xF2C00000 | uint32(value>>32&0xFFFF)<<5 | register,
+		0xF2E00000 | uint32(value>>48&0xFFFF)<<5 | register,
+	}
+}
+
+func encode(instructions ...uint32) []byte {
+	code := make([]byte, 4*len(instructions))
+	for i, instruction := range instructions {
+		binary.LittleEndian.PutUint32(code[4*i:], instruction)
+	}
+
+	return code
+}
+
+func isBranch(word uint32) bool {
+	return word&0xFC000000 == 0x14000000
+}
+
+// BL and BLR
+func isCall(word uint32) bool {
+	return word&0xFC000000 == 0x94000000 || word&0xFFFFFC1F == 0xD63F0000
+}
+
+// B.cond
+func isConditionalBranch(word uint32) bool {
+	return word&0xFF000010 == 0x54000000
+}
+
+// CBZ and CBNZ
+func isCompareBranch(word uint32) bool {
+	return word&0x7E000000 == 0x34000000
+}
+
+// TBZ and TBNZ
+func isTestBranch(word uint32) bool {
+	return word&0x7E000000 == 0x36000000
+}
+
+// ADR and ADRP
+func isAddressOf(word uint32) bool {
+	return word&0x1F000000 == 0x10000000
+}
+
+// LDR, LDRSW and PRFM of a PC-relative literal
+func isLiteralLoad(word uint32) bool {
+	return word&0x3B000000 == 0x18000000
+}
+
+// Execution doesn't go on to the next instruction: B, BR, RET, BRK, or the zero padding between functions
+func terminates(word uint32) bool {
+	return isBranch(word) || word&0xFFFFFC1F == 0xD61F0000 || word&0xFFFFFC1F == 0xD65F0000 ||
+		word&0xFFE0001F == 0xD4200000 || word == 0
+}
+
+func branchTarget(addr uintptr, word uint32) uintptr {
+	switch {
+	case isBranch(word):
+		return addr + uintptr(signExtend(word&0x3FFFFFF, 26)*4)
+	case isTestBranch(word):
+		return addr + uintptr(signExtend(word>>5&0x3FFF, 14)*4)
+	default:
+		return addr + uintptr(signExtend(word>>5&0x7FFFF, 19)*4)
+	}
+}
+
+// The conditional branch, branching offset bytes ahead instead
+func withOffset(word uint32, offset uint32) uint32 {
+	if isTestBranch(word) {
+		return word&^(0x3FFF<<5) | (offset/4)<<5
+	}
+
+	return word&^(0x7FFFF<<5) | (offset/4)<<5
+}
+
+func addressOf(addr uintptr, word uint32) uintptr {
+	immediate := signExtend(word>>5&0x7FFFF<<2|word>>29&0x3, 21)
+	if word&0x80000000 != 0 {
+		return addr&^0xFFF + uintptr(immediate<<12)
+	}
+
+	return addr + uintptr(immediate)
+}
+
+func signExtend(value uint32, bits uint) int64 {
+	return int64(value<<(32-bits)) << 32 >> (64 - bits)
+}
+
+// Instruction by instruction, so a thread running the code sees each one either before or after
+func storeCode(addr uintptr, code []byte) {
+	bytes := rawMemory(addr, len(code))
+	words := unsafe.Slice((*uint32)(unsafe.Pointer(&bytes[0])), len(code)/4)
+
+	for i := range words {
+		atomic.StoreUint32(&words[i], binary.LittleEndian.Uint32(code[4*i:]))
+	}
+}
+
+// The instruction cache isn't coherent with writes to code, so it's invalidated. In cache_arm64.s
+func flushCode(addr uintptr, length int)
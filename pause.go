@@ -0,0 +1,41 @@
+package gospy
+
+// Stops recording calls, while the spy stays installed and keeps faking
+func (self *GoSpy) Pause() {
+	self.setPaused(true, false)
+}
+
+// Stops recording calls, and sends them to the original function instead of the fake
+func (self *GoSpy) PauseAndPassThrough() {
+	self.setPaused(true, true)
+}
+
+func (self *GoSpy) Resume() {
+	self.setPaused(false, false)
+}
+
+func (self *GoSpy) Paused() bool {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	return self.paused
+}
+
+// Runs body without recording its calls, then goes back to the previous state, even if body panics
+func (self *GoSpy) Ignoring(body func()) {
+	self.mutex.Lock()
+	paused, passThrough := self.paused, self.passThrough
+	self.mutex.Unlock()
+
+	self.setPaused(true, passThrough)
+	defer self.setPaused(paused, passThrough)
+
+	body()
+}
+
+func (self *GoSpy) setPaused(paused bool, passThrough bool) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.paused, self.passThrough = paused, passThrough
+}
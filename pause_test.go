@@ -0,0 +1,94 @@
+package gospy_test
+
+import (
+	. "github.com/cfmobile/gospy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Pausing", func() {
+	var subject *GoSpy
+	var lookup func(string) string
+
+	BeforeEach(func() {
+		lookup = func(key string) string {
+			return "original " + key
+		}
+		subject = SpyAndFakeWithReturn(&lookup, "fake")
+	})
+
+	AfterEach(func() {
+		subject.Restore()
+	})
+
+	Context("when Pause() is called", func() {
+		BeforeEach(func() {
+			lookup("before")
+			subject.Pause()
+		})
+
+		It("should stop recording calls but keep faking", func() {
+			Expect(lookup("paused")).To(Equal("fake"))
+
+			Expect(subject.Paused()).To(BeTrue())
+			Expect(subject.Calls()).To(Equal(CallList{{"before"}}))
+		})
+
+		It("should record calls again after Resume()", func() {
+			lookup("paused")
+			subject.Resume()
+			lookup("after")
+
+			Expect(subject.Paused()).To(BeFalse())
+			Expect(subject.Calls()).To(Equal(CallList{{"before"}, {"after"}}))
+		})
+	})
+
+	Context("when PauseAndPassThrough() is called", func() {
+		BeforeEach(func() {
+			subject.PauseAndPassThrough()
+		})
+
+		It("should stop recording and send calls to the original", func() {
+			Expect(lookup("paused")).To(Equal("original paused"))
+			Expect(subject.Called()).To(BeFalse())
+		})
+
+		It("should fake again after Resume()", func() {
+			subject.Resume()
+
+			Expect(lookup("after")).To(Equal("fake"))
+			Expect(subject.CallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when calls are made inside Ignoring()", func() {
+		It("should only record the calls made outside", func() {
+			lookup("before")
+			subject.Ignoring(func() {
+				Expect(lookup("ignored")).To(Equal("fake"))
+			})
+			lookup("after")
+
+			Expect(subject.Calls()).To(Equal(CallList{{"before"}, {"after"}}))
+		})
+
+		It("should go back to the previous state even if the body panics", func() {
+			func() {
+				defer func() { recover() }()
+				subject.Ignoring(func() {
+					panic("setup failed")
+				})
+			}()
+
+			Expect(subject.Paused()).To(BeFalse())
+		})
+
+		It("should stay paused afterwards if it was already paused", func() {
+			subject.Pause()
+			subject.Ignoring(func() {})
+
+			Expect(subject.Paused()).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,169 @@
+package gospy
+
+import (
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// A recorded call, as returned by queries
+type Call struct {
+	Index      int
+	Seq        uint64
+	Time       time.Time
+	Args       ArgList
+	Returns    []interface{}
+	PanicValue interface{}
+	Panicked   bool
+	Callbacks  []CallbackCall
+	Origin     *CallOrigin
+}
+
+// Recorded calls, in the order they were made, with methods to narrow them down
+type CallQuery []Call
+
+// Sequence numbers order calls across every spy
+var callSequence uint64
+
+// Sequence number of the last call made to any spy. Calls made after it are returned by Since()
+func CurrentSeq() uint64 {
+	return atomic.LoadUint64(&callSequence)
+}
+
+// The calls kept, as Calls() lists them, so only the indices listed by
+// CallIndices() are there when SetRecording() drops calls
+func (self *GoSpy) Query() CallQuery {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	query := make(CallQuery, len(self.calls))
+	for i, args := range self.calls {
+		query[i] = self.records[i].call(args)
+	}
+
+	return query
+}
+
+// Must be called with the spy's mutex held
+func (self *callRecord) call(args ArgList) Call {
+	return Call{
+		Index:      self.index,
+		Seq:        self.seq,
+		Time:       self.time,
+		Args:       args,
+		Returns:    self.returns,
+		PanicValue: self.panicValue,
+		Panicked:   self.panicked,
+		Callbacks:  append([]CallbackCall(nil), self.callbacks...),
+		Origin:     self.origin,
+	}
+}
+
+func (self CallQuery) First() Call {
+	if len(self) == 0 {
+		panic("No calls to get the first of")
+	}
+
+	return self[0]
+}
+
+func (self CallQuery) Last() Call {
+	if len(self) == 0 {
+		panic("No calls to get the last of")
+	}
+
+	return self[len(self)-1]
+}
+
+// Calls whose arguments match the matchers or values at their positions, see ArgsMatch()
+func (self CallQuery) Filter(matchers ...interface{}) CallQuery {
+	var filtered CallQuery
+	for _, call := range self {
+		if ArgsMatch(call.Args, matchers...) {
+			filtered = append(filtered, call)
+		}
+	}
+
+	return filtered
+}
+
+func (self CallQuery) Count(matchers ...interface{}) int {
+	return len(self.Filter(matchers...))
+}
+
+func (self CallQuery) Any(predicate func(args ArgList) bool) bool {
+	for _, call := range self {
+		if predicate(call.Args) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (self CallQuery) All(predicate func(args ArgList) bool) bool {
+	for _, call := range self {
+		if !predicate(call.Args) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Values of argument i across the calls
+func (self CallQuery) Column(i int) []interface{} {
+	column := make([]interface{}, len(self))
+	for position, call := range self {
+		if i < 0 || i >= len(call.Args) {
+			panic(fmt.Sprintf("Call %d has no argument %d [args: %d]", call.Index, i, len(call.Args)))
+		}
+		column[position] = call.Args[i]
+	}
+
+	return column
+}
+
+// Values of argument i across the calls, without repetitions, in the order they were first seen
+func (self CallQuery) Distinct(i int) []interface{} {
+	var distinct []interface{}
+	for _, value := range self.Column(i) {
+		if !containsDeepEqual(distinct, value) {
+			distinct = append(distinct, value)
+		}
+	}
+
+	return distinct
+}
+
+// Calls made after the one with sequence number seq, as returned by CurrentSeq()
+func (self CallQuery) Since(seq uint64) CallQuery {
+	var since CallQuery
+	for _, call := range self {
+		if call.Seq > seq {
+			since = append(since, call)
+		}
+	}
+
+	return since
+}
+
+func (self CallQuery) Args() CallList {
+	calls := make(CallList, len(self))
+	for i, call := range self {
+		calls[i] = call.Args
+	}
+
+	return calls
+}
+
+func containsDeepEqual(values []interface{}, value interface{}) bool {
+	for _, candidate := range values {
+		if reflect.DeepEqual(candidate, value) {
+			return true
+		}
+	}
+
+	return false
+}
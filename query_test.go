@@ -0,0 +1,91 @@
+package gospy_test
+
+import (
+	. "github.com/cfmobile/gospy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Querying calls", func() {
+	var subject *GoSpy
+	var send func(string, int) error
+
+	BeforeEach(func() {
+		send = func(string, int) error {
+			return nil
+		}
+		subject = Spy(&send)
+
+		send("ann", 1)
+		send("bob", 2)
+		send("ann", 3)
+	})
+
+	AfterEach(func() {
+		subject.Restore()
+	})
+
+	It("should return every recorded call with its index and returns", func() {
+		query := subject.Query()
+
+		Expect(query).To(HaveLen(3))
+		Expect(query.Args()).To(Equal(subject.Calls()))
+		Expect(query[1].Index).To(Equal(1))
+		Expect(query[1].Returns).To(Equal([]interface{}{nil}))
+	})
+
+	It("should return the first and last calls", func() {
+		Expect(subject.Query().First().Args).To(Equal(ArgList{"ann", 1}))
+		Expect(subject.Query().Last().Args).To(Equal(ArgList{"ann", 3}))
+	})
+
+	It("should refuse to return the first or last of no calls", func() {
+		Expect(func() { CallQuery{}.First() }).To(Panic())
+		Expect(func() { CallQuery{}.Last() }).To(Panic())
+	})
+
+	It("should filter and count the calls by argument", func() {
+		Expect(subject.Query().Filter("ann").Args()).To(Equal(CallList{{"ann", 1}, {"ann", 3}}))
+		Expect(subject.Query().Count("ann", BeNumerically(">", 1))).To(Equal(1))
+		Expect(subject.Query().Count(AnyArg(), 2)).To(Equal(1))
+		Expect(subject.Query().Count()).To(Equal(3))
+	})
+
+	It("should check whether any or all calls satisfy a predicate", func() {
+		fromAnn := func(args ArgList) bool {
+			return args[0] == "ann"
+		}
+
+		Expect(subject.Query().Any(fromAnn)).To(BeTrue())
+		Expect(subject.Query().All(fromAnn)).To(BeFalse())
+		Expect(subject.Query().Filter("ann").All(fromAnn)).To(BeTrue())
+	})
+
+	It("should return the values of an argument", func() {
+		Expect(subject.Query().Column(0)).To(Equal([]interface{}{"ann", "bob", "ann"}))
+		Expect(subject.Query().Distinct(0)).To(Equal([]interface{}{"ann", "bob"}))
+	})
+
+	It("should refuse to return an argument the calls don't have", func() {
+		Expect(func() { subject.Query().Column(2) }).To(Panic())
+	})
+
+	It("should return the calls made since a sequence number", func() {
+		mark := CurrentSeq()
+		send("carl", 4)
+
+		Expect(subject.Query().Since(mark).Args()).To(Equal(CallList{{"carl", 4}}))
+	})
+
+	It("should order calls across spies by sequence number", func() {
+		notify := func() {}
+		other := Spy(&notify)
+		defer other.Restore()
+
+		notify()
+		send("dan", 5)
+
+		Expect(other.Query().First().Seq).To(BeNumerically("<", subject.Query().Last().Seq))
+		Expect(subject.Query()[0].Seq).To(BeNumerically("<", other.Query().First().Seq))
+	})
+})
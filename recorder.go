@@ -0,0 +1,105 @@
+package gospy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// One ordered log of the calls recorded by several spies
+type Recorder struct {
+	mutex   sync.Mutex
+	entries []recorderEntry
+}
+
+// A call in the log of a Recorder, with the name of the spy that recorded it
+type RecordedCall struct {
+	Spy  string
+	Call Call
+}
+
+type recorderEntry struct {
+	spy    *GoSpy
+	record *callRecord
+	args   ArgList
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Logs the calls the spies record from now on
+func (self *Recorder) Attach(spies ...*GoSpy) *Recorder {
+	for _, spy := range spies {
+		spy.mutex.Lock()
+		spy.recorders = append(spy.recorders, self)
+		spy.mutex.Unlock()
+	}
+
+	return self
+}
+
+// The calls logged, ordered by sequence number, with their returns as they are now
+func (self *Recorder) Entries() []RecordedCall {
+	self.mutex.Lock()
+	entries := append([]recorderEntry(nil), self.entries...)
+	self.mutex.Unlock()
+
+	recorded := make([]RecordedCall, len(entries))
+	for i, entry := range entries {
+		entry.spy.mutex.Lock()
+		recorded[i] = RecordedCall{Spy: entry.spy.name, Call: entry.record.call(entry.args)}
+		entry.spy.mutex.Unlock()
+
+		if recorded[i].Spy == "" {
+			recorded[i].Spy = entry.spy.derivedName()
+		}
+	}
+
+	sort.SliceStable(recorded, func(i, j int) bool {
+		return recorded[i].Call.Seq < recorded[j].Call.Seq
+	})
+
+	return recorded
+}
+
+func (self *Recorder) Reset() {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.entries = nil
+}
+
+// The log as a timeline, one call per line
+func (self *Recorder) String() string {
+	entries := self.Entries()
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "Recorder: %d %s", len(entries), pluralise(len(entries), "call", "calls"))
+
+	for _, entry := range entries {
+		fmt.Fprintf(&builder, "\n  %s", entry)
+	}
+
+	return builder.String()
+}
+
+func (self RecordedCall) String() string {
+	description := fmt.Sprintf("#%d %s%s", self.Call.Seq, self.Spy, FormatArgs(self.Call.Args))
+
+	if self.Call.Panicked {
+		description += " panicked: " + FormatValue(self.Call.PanicValue)
+	} else if len(self.Call.Returns) > 0 {
+		description += " => " + FormatArgs(self.Call.Returns)
+	}
+
+	return description
+}
+
+func (self *Recorder) add(spy *GoSpy, record *callRecord, args ArgList) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.entries = append(self.entries, recorderEntry{spy: spy, record: record, args: args})
+}
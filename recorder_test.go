@@ -0,0 +1,80 @@
+package gospy_test
+
+import (
+	"errors"
+	. "github.com/cfmobile/gospy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"strings"
+)
+
+var _ = Describe("Recorder", func() {
+	var connect func(string) error
+	var send func(string) (int, error)
+	var upper func(string) string
+	var registry *Registry
+	var recorder *Recorder
+
+	BeforeEach(func() {
+		connect = func(string) error { return nil }
+		send = func(message string) (int, error) { return len(message), nil }
+		upper = strings.ToUpper
+
+		registry = NewRegistry()
+		recorder = NewRecorder().Attach(
+			registry.Spy(&connect).Named("connect"),
+			registry.SpyAndFakeWithReturn(&send, 0, errors.New("broken pipe")).Named("send"),
+		)
+	})
+
+	AfterEach(func() {
+		registry.RestoreAll()
+	})
+
+	It("should keep one ordered log of the calls of every attached spy", func() {
+		connect("db")
+		send("a")
+		send("b")
+
+		entries := recorder.Entries()
+		Expect(entries).To(HaveLen(3))
+		Expect(entries[0].Spy).To(Equal("connect"))
+		Expect(entries[1].Spy).To(Equal("send"))
+		Expect(entries[2].Call.Args).To(Equal(ArgList{"b"}))
+		Expect(entries[0].Call.Seq).To(BeNumerically("<", entries[1].Call.Seq))
+	})
+
+	It("should only log calls made after attaching the spy", func() {
+		upperSpy := registry.Spy(&upper)
+		upper("early")
+		recorder.Attach(upperSpy)
+		upper("late")
+
+		Expect(recorder.Entries()).To(HaveLen(1))
+		Expect(recorder.Entries()[0].Call.Args).To(Equal(ArgList{"late"}))
+	})
+
+	It("should derive the name of spies from the function they were created on", func() {
+		recorder.Attach(registry.Spy(&upper))
+		upper("a")
+
+		Expect(recorder.Entries()[0].Spy).To(Equal("strings.ToUpper"))
+	})
+
+	It("should print the log as one timeline", func() {
+		connect("db")
+		send("a")
+
+		timeline := recorder.String()
+		Expect(timeline).To(HavePrefix("Recorder: 2 calls"))
+		Expect(timeline).To(MatchRegexp(`\n  #\d+ connect\("db"\) => \(nil\)`))
+		Expect(timeline).To(MatchRegexp(`\n  #\d+ send\("a"\) => \(0, error\("broken pipe"\)\)`))
+	})
+
+	It("should forget the calls when reset", func() {
+		connect("db")
+		recorder.Reset()
+
+		Expect(recorder.Entries()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,167 @@
+package gospy
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+type RecordingOption func(*recordingConfig)
+
+type recordingConfig struct {
+	countOnly   bool
+	keepLast    int
+	sampleEvery int
+	predicate   func(args ArgList) bool
+
+	captureCallers bool
+	stackDepth     int
+	callerFilter   *regexp.Regexp
+}
+
+// Counts calls without keeping their arguments
+func CountOnly() RecordingOption {
+	return func(config *recordingConfig) {
+		config.countOnly = true
+	}
+}
+
+// Keeps only the most recent n calls, dropping older ones as new calls come in
+func KeepLast(n int) RecordingOption {
+	return func(config *recordingConfig) {
+		config.keepLast = n
+	}
+}
+
+// Keeps one call in every k: calls 0, k, 2k...
+func SampleEvery(k int) RecordingOption {
+	return func(config *recordingConfig) {
+		config.sampleEvery = k
+	}
+}
+
+func RecordOnly(predicate func(args ArgList) bool) RecordingOption {
+	return func(config *recordingConfig) {
+		config.predicate = predicate
+	}
+}
+
+// Only counts and records the calls made from a function whose qualified name
+// matches pattern, anywhere in the stack. Other calls go to the original function
+// as if the spy wasn't installed, so a fake only answers the calls of interest
+func OnlyFrom(pattern string) RecordingOption {
+	matcher, err := regexp.Compile(pattern)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid caller pattern %q: %s", pattern, err.Error()))
+	}
+
+	return func(config *recordingConfig) {
+		config.callerFilter = matcher
+	}
+}
+
+// Only counts and records the calls made from the package with the given import path
+func OnlyFromPackage(packagePath string) RecordingOption {
+	return OnlyFrom("^" + regexp.QuoteMeta(packagePath) + `\.`)
+}
+
+// Changes which of the subsequent calls are kept. Calling it with no options
+// goes back to keeping every call. CallCount() stays exact regardless
+func (self *GoSpy) SetRecording(options ...RecordingOption) {
+	var config recordingConfig
+	for _, option := range options {
+		option(&config)
+	}
+
+	if err := recordingConfigIsValid(config); err != nil {
+		panic(err.Error())
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.recording = config
+}
+
+// Indices of the calls still kept by the spy, as accepted by ArgsForCall()
+func (self *GoSpy) CallIndices() []int {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	indices := make([]int, len(self.records))
+	for i, record := range self.records {
+		indices[i] = record.index
+	}
+
+	return indices
+}
+
+func (self recordingConfig) mayRecord(callIndex int) bool {
+	if self.countOnly {
+		return false
+	}
+
+	return self.sampleEvery <= 1 || callIndex%self.sampleEvery == 0
+}
+
+// Calls are counted and kept under separate locks, so a call may be kept after
+// later ones. It is inserted by index, keeping records sorted for positionOf.
+// Must be called with the mutex held
+func (self *GoSpy) keep(record *callRecord, call ArgList, keepLast int) {
+	position := sort.Search(len(self.records), func(i int) bool {
+		return self.records[i].index > record.index
+	})
+
+	self.calls = append(self.calls, nil)
+	copy(self.calls[position+1:], self.calls[position:])
+	self.calls[position] = call
+
+	self.records = append(self.records, nil)
+	copy(self.records[position+1:], self.records[position:])
+	self.records[position] = record
+
+	if keepLast > 0 && len(self.calls) > keepLast {
+		self.calls = self.calls[len(self.calls)-keepLast:]
+		self.records = self.records[len(self.records)-keepLast:]
+	}
+}
+
+// Position in calls of the call with the given index, or -1 if it isn't kept
+func (self *GoSpy) positionOf(callIndex int) int {
+	position := sort.Search(len(self.records), func(i int) bool {
+		return self.records[i].index >= callIndex
+	})
+
+	if position == len(self.records) || self.records[position].index != callIndex {
+		return -1
+	}
+
+	return position
+}
+
+// Panics if the call isn't recorded, like the accessors taking a call index
+func (self *GoSpy) mustPositionOf(callIndex int) int {
+	position := self.positionOf(callIndex)
+	if position < 0 {
+		panic(fmt.Sprintf("Call %d is not recorded by the spy [calls: %d, recorded: %d]", callIndex, self.count, len(self.calls)))
+	}
+
+	return position
+}
+
+func recordingConfigIsValid(config recordingConfig) error {
+	if config.keepLast < 0 {
+		return errors.New(fmt.Sprintf("Number of calls to keep can't be negative [keepLast: %d]", config.keepLast))
+	}
+
+	if config.sampleEvery < 0 {
+		return errors.New(fmt.Sprintf("Sampling rate can't be negative [sampleEvery: %d]", config.sampleEvery))
+	}
+
+	if config.stackDepth < 0 {
+		return errors.New(fmt.Sprintf("Stack depth can't be negative [stackDepth: %d]", config.stackDepth))
+	}
+
+	return nil
+}
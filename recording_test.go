@@ -0,0 +1,201 @@
+package gospy_test
+
+import (
+	. "github.com/cfmobile/gospy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"sync"
+	"time"
+)
+
+var _ = Describe("Recording options", func() {
+	var subject *GoSpy
+	var process func(int) int
+	var panicked bool
+
+	BeforeEach(func() {
+		panicked = false
+		process = func(i int) int {
+			return i
+		}
+		subject = Spy(&process)
+	})
+
+	AfterEach(func() {
+		subject.Restore()
+	})
+
+	callTimes := func(n int) {
+		for i := 0; i < n; i++ {
+			process(i)
+		}
+	}
+
+	argsForCallPanics := func(callIndex uint) {
+		defer func() {
+			panicked = recover() != nil
+		}()
+		subject.ArgsForCall(callIndex)
+	}
+
+	Context("when keeping only the last calls", func() {
+		BeforeEach(func() {
+			subject.SetRecording(KeepLast(3))
+			callTimes(10)
+		})
+
+		It("should keep an exact call count", func() {
+			Expect(subject.CallCount()).To(Equal(10))
+		})
+
+		It("should only keep the most recent calls", func() {
+			Expect(subject.Calls()).To(Equal(CallList{{7}, {8}, {9}}))
+			Expect(subject.CallIndices()).To(Equal([]int{7, 8, 9}))
+		})
+
+		It("should find kept calls by their call index", func() {
+			Expect(subject.ArgsForCall(8)).To(Equal(ArgList{8}))
+		})
+
+		It("should panic when asking for a call that was dropped", func() {
+			argsForCallPanics(2)
+
+			Expect(panicked).To(BeTrue())
+		})
+	})
+
+	Context("when only counting calls", func() {
+		BeforeEach(func() {
+			subject.SetRecording(CountOnly())
+			callTimes(5)
+		})
+
+		It("should count every call without keeping any", func() {
+			Expect(subject.CallCount()).To(Equal(5))
+			Expect(subject.Called()).To(BeTrue())
+			Expect(subject.Calls()).To(BeNil())
+		})
+	})
+
+	Context("when sampling calls", func() {
+		BeforeEach(func() {
+			subject.SetRecording(SampleEvery(4))
+			callTimes(10)
+		})
+
+		It("should keep one call in every k", func() {
+			Expect(subject.CallCount()).To(Equal(10))
+			Expect(subject.CallIndices()).To(Equal([]int{0, 4, 8}))
+			Expect(subject.ArgsForCall(4)).To(Equal(ArgList{4}))
+		})
+	})
+
+	Context("when only recording calls matching a predicate", func() {
+		BeforeEach(func() {
+			subject.SetRecording(RecordOnly(func(args ArgList) bool {
+				return args[0].(int)%3 == 0
+			}), KeepLast(2))
+			callTimes(10)
+		})
+
+		It("should combine with the other options", func() {
+			Expect(subject.CallCount()).To(Equal(10))
+			Expect(subject.Calls()).To(Equal(CallList{{6}, {9}}))
+		})
+
+		It("should show the kept calls with their call index", func() {
+			Expect(subject.String()).To(ContainSubstring("10 calls (2 recorded)"))
+			Expect(subject.String()).To(ContainSubstring("#9 (9) => (9)"))
+		})
+	})
+
+	Context("when calls are kept in a different order than they were counted", func() {
+		slowOnEven := RecordOnly(func(args ArgList) bool {
+			if args[0].(int)%2 == 0 {
+				time.Sleep(time.Millisecond)
+			}
+			return true
+		})
+
+		callConcurrently := func(n int) {
+			var wait sync.WaitGroup
+			for i := 0; i < n; i++ {
+				wait.Add(1)
+				go func(i int) {
+					defer wait.Done()
+					process(i)
+				}(i)
+			}
+			wait.Wait()
+		}
+
+		It("should keep every call findable by its call index", func() {
+			subject.SetRecording(slowOnEven)
+			callConcurrently(50)
+
+			indices := subject.CallIndices()
+			Expect(indices).To(HaveLen(50))
+			for i, callIndex := range indices {
+				Expect(callIndex).To(Equal(i))
+				Expect(subject.ArgsForCall(uint(callIndex))).To(Equal(subject.Calls()[i]))
+			}
+		})
+
+		It("should hand out call lists that later calls don't change", func() {
+			subject.SetRecording(slowOnEven)
+			done := make(chan struct{})
+			go func() {
+				callConcurrently(50)
+				close(done)
+			}()
+
+			var handedOut, copies []CallList
+			for finished := false; !finished; {
+				select {
+				case <-done:
+					finished = true
+				default:
+				}
+
+				calls := subject.Calls()
+				handedOut = append(handedOut, calls)
+				copies = append(copies, append(CallList(nil), calls...))
+			}
+
+			Expect(handedOut).To(Equal(copies))
+		})
+
+		It("should keep the calls with the last indices", func() {
+			subject.SetRecording(slowOnEven, KeepLast(10))
+			callConcurrently(50)
+
+			Expect(subject.CallIndices()).To(Equal([]int{40, 41, 42, 43, 44, 45, 46, 47, 48, 49}))
+		})
+	})
+
+	Context("when recording is set back to the default", func() {
+		BeforeEach(func() {
+			subject.SetRecording(CountOnly())
+			callTimes(2)
+			subject.SetRecording()
+			callTimes(1)
+		})
+
+		It("should keep the subsequent calls", func() {
+			Expect(subject.CallIndices()).To(Equal([]int{2}))
+		})
+	})
+
+	Context("when given invalid options", func() {
+		BeforeEach(func() {
+			defer func() {
+				panicked = recover() != nil
+			}()
+			subject.SetRecording(KeepLast(-1))
+		})
+
+		It("should have panicked", func() {
+			Expect(panicked).To(BeTrue())
+		})
+	})
+})
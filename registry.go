@@ -0,0 +1,141 @@
+package gospy
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Installs a spy on a target, choosing how it behaves
+type Behaviour func(targetFuncPtr interface{}) *GoSpy
+
+func PassThrough() Behaviour {
+	return Spy
+}
+
+func Fake() Behaviour {
+	return SpyAndFake
+}
+
+func FakeWithReturn(fakeReturnValues ...interface{}) Behaviour {
+	return func(targetFuncPtr interface{}) *GoSpy {
+		return SpyAndFakeWithReturn(targetFuncPtr, fakeReturnValues...)
+	}
+}
+
+func FakeWithFunc(mockFunc interface{}) Behaviour {
+	return func(targetFuncPtr interface{}) *GoSpy {
+		return SpyAndFakeWithFunc(targetFuncPtr, mockFunc)
+	}
+}
+
+func FakeWithCallFunc(callFunc func(c *CallContext, args ...interface{}) []interface{}) Behaviour {
+	return func(targetFuncPtr interface{}) *GoSpy {
+		return SpyAndFakeWithCallFunc(targetFuncPtr, callFunc)
+	}
+}
+
+// Keeps track of a group of spies so they can be inspected and restored together
+type Registry struct {
+	mutex sync.Mutex
+	spies []*GoSpy
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (self *Registry) Install(targetFuncPtr interface{}, behaviour Behaviour) *GoSpy {
+	if behaviour == nil {
+		behaviour = PassThrough()
+	}
+
+	return self.Add(behaviour(targetFuncPtr))
+}
+
+func (self *Registry) Spy(targetFuncPtr interface{}) *GoSpy {
+	return self.Install(targetFuncPtr, PassThrough())
+}
+
+func (self *Registry) SpyAndFake(targetFuncPtr interface{}) *GoSpy {
+	return self.Install(targetFuncPtr, Fake())
+}
+
+func (self *Registry) SpyAndFakeWithReturn(targetFuncPtr interface{}, fakeReturnValues ...interface{}) *GoSpy {
+	return self.Install(targetFuncPtr, FakeWithReturn(fakeReturnValues...))
+}
+
+func (self *Registry) SpyAndFakeWithFunc(targetFuncPtr interface{}, mockFunc interface{}) *GoSpy {
+	return self.Install(targetFuncPtr, FakeWithFunc(mockFunc))
+}
+
+func (self *Registry) Add(spy *GoSpy) *GoSpy {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.spies = append(self.spies, spy)
+	return spy
+}
+
+func (self *Registry) Spies() []*GoSpy {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	return append([]*GoSpy(nil), self.spies...)
+}
+
+// The spy with the given name, or nil if there is none
+func (self *Registry) Lookup(name string) *GoSpy {
+	for _, spy := range self.Spies() {
+		if spy.Name() == name {
+			return spy
+		}
+	}
+
+	return nil
+}
+
+// The calls recorded by the spies, ordered by sequence number
+func (self *Registry) Entries() []RecordedCall {
+	var entries []RecordedCall
+	for _, spy := range self.Spies() {
+		name := spy.Name()
+		for _, call := range spy.Query() {
+			entries = append(entries, RecordedCall{Spy: name, Call: call})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Call.Seq < entries[j].Call.Seq
+	})
+
+	return entries
+}
+
+func (self *Registry) ResetAll() {
+	for _, spy := range self.Spies() {
+		spy.Reset()
+	}
+}
+
+// Restores the spies innermost first, and forgets about them
+func (self *Registry) RestoreAll() {
+	spies := self.Spies()
+
+	self.mutex.Lock()
+	self.spies = nil
+	self.mutex.Unlock()
+
+	for i := len(spies) - 1; i >= 0; i-- {
+		spies[i].Restore()
+	}
+}
+
+func (self *Registry) String() string {
+	var logs []string
+	for _, spy := range self.Spies() {
+		logs = append(logs, spy.String())
+	}
+
+	return strings.Join(logs, "\n")
+}
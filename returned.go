@@ -0,0 +1,75 @@
+package gospy
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Wraps every func returned by subsequent calls, by the original or a fake, in
+// a child spy. Children spy on the funcs they return too
+func (self *GoSpy) SpyOnReturnedFuncs() *GoSpy {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.spyReturns = true
+	return self
+}
+
+// The child spy wrapping the func returned at returnIndex by the call
+func (self *GoSpy) ReturnedSpy(callIndex uint, returnIndex int) *GoSpy {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	position := self.mustPositionOf(int(callIndex))
+
+	child, ok := self.records[position].children[returnIndex]
+	if !ok {
+		panic(fmt.Sprintf("Call %d returned no spied func at %d. Only non-nil funcs returned once SpyOnReturnedFuncs() is set are spied on", callIndex, returnIndex))
+	}
+
+	return child
+}
+
+// Wraps behaviour so the funcs it returns are replaced by child spies, kept in record
+func (self *GoSpy) withReturnedSpies(record *callRecord, behaviour func([]reflect.Value) []reflect.Value) func([]reflect.Value) []reflect.Value {
+	return func(args []reflect.Value) []reflect.Value {
+		results := behaviour(args)
+
+		self.mutex.Lock()
+		recorded := record != nil && record.recorded
+		self.mutex.Unlock()
+
+		if !recorded {
+			return results
+		}
+
+		// Fakes may answer every call with the same slice
+		results = append([]reflect.Value(nil), results...)
+		children := make(map[int]*GoSpy)
+		for i, result := range results {
+			if result.Kind() == reflect.Func && !result.IsNil() {
+				children[i] = newReturnedSpy(result)
+				results[i] = children[i].wrapper
+			}
+		}
+
+		self.mutex.Lock()
+		record.children = children
+		self.mutex.Unlock()
+
+		return results
+	}
+}
+
+// Child spies are not installed anywhere: their wrapper is handed to the caller instead of the func
+func newReturnedSpy(fn reflect.Value) *GoSpy {
+	fnVar := reflect.New(fn.Type())
+	fnVar.Elem().Set(fn)
+
+	child := createSpy(fnVar.Interface())
+	child.setBehaviour(child.getDefaultFn(), child.getDefaultFast())
+	child.spyReturns = true
+	child.wrapper = child.newWrapper()
+
+	return child
+}
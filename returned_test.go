@@ -0,0 +1,84 @@
+package gospy_test
+
+import (
+	"errors"
+	. "github.com/cfmobile/gospy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Spying on returned funcs", func() {
+	var newClient func(string) (func(string) (string, error), error)
+
+	BeforeEach(func() {
+		newClient = func(host string) (func(string) (string, error), error) {
+			return func(request string) (string, error) {
+				return host + " answered " + request, nil
+			}, nil
+		}
+	})
+
+	It("should wrap the funcs returned by the original in child spies", func() {
+		spy := Spy(&newClient).SpyOnReturnedFuncs()
+		defer spy.Restore()
+
+		client, _ := newClient("a.com")
+		Expect(client("ping")).To(Equal("a.com answered ping"))
+
+		child := spy.ReturnedSpy(0, 0)
+		Expect(child.Calls()).To(Equal(CallList{{"ping"}}))
+		Expect(child.String()).To(ContainSubstring(`#0 ("ping") => ("a.com answered ping", nil)`))
+	})
+
+	It("should wrap the funcs returned by fakes, with a child spy per call", func() {
+		fakeClient := func(string) (string, error) {
+			return "", errors.New("fake")
+		}
+		spy := SpyAndFakeWithReturn(&newClient, fakeClient, nil)
+		spy.SpyOnReturnedFuncs()
+		defer spy.Restore()
+
+		first, _ := newClient("a.com")
+		second, _ := newClient("b.com")
+		first("ping")
+		second("pong")
+		second("pong")
+
+		Expect(spy.ReturnedSpy(0, 0).CallCount()).To(Equal(1))
+		Expect(spy.ReturnedSpy(1, 0).CallCount()).To(Equal(2))
+	})
+
+	It("should spy on the funcs returned by children", func() {
+		var newFactory func() func() func() int
+		newFactory = func() func() func() int {
+			return func() func() int {
+				return func() int { return 42 }
+			}
+		}
+		spy := Spy(&newFactory).SpyOnReturnedFuncs()
+		defer spy.Restore()
+
+		Expect(newFactory()()()).To(Equal(42))
+		Expect(spy.ReturnedSpy(0, 0).ReturnedSpy(0, 0).Called()).To(BeTrue())
+	})
+
+	It("should refuse to return a spy for values that are not funcs, or nil", func() {
+		spy := SpyAndFake(&newClient).SpyOnReturnedFuncs()
+		defer spy.Restore()
+
+		newClient("a.com")
+
+		Expect(func() { spy.ReturnedSpy(0, 0) }).To(Panic())
+		Expect(func() { spy.ReturnedSpy(0, 1) }).To(Panic())
+		Expect(func() { spy.ReturnedSpy(1, 0) }).To(Panic())
+	})
+
+	It("should not wrap returned funcs unless asked to", func() {
+		spy := Spy(&newClient)
+		defer spy.Restore()
+
+		newClient("a.com")
+
+		Expect(func() { spy.ReturnedSpy(0, 0) }).To(Panic())
+	})
+})
@@ -0,0 +1,160 @@
+package gospy
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Computes return values from the arguments of each call. Can be passed to
+// SpyAndFakeWithReturn() in place of a literal value
+type ReturnBuilder struct {
+	argIndex int
+	field    string
+	compute  func(args ArgList) []interface{}
+}
+
+// Returns argument i as is
+func ReturnArg(i int) *ReturnBuilder {
+	return &ReturnBuilder{argIndex: i}
+}
+
+// Returns the exported field of argument i, a struct or a pointer to one
+func ReturnArgField(i int, field string) *ReturnBuilder {
+	return &ReturnBuilder{argIndex: i, field: field}
+}
+
+// Computes every return value of the call. Can't be mixed with other values
+func ReturnFrom(compute func(args ArgList) []interface{}) *ReturnBuilder {
+	return &ReturnBuilder{compute: compute}
+}
+
+func hasReturnBuilders(fakeReturnValues []interface{}) bool {
+	for i := range fakeReturnValues {
+		if _, ok := returnBuilderAt(fakeReturnValues, i); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func returnBuilderAt(fakeReturnValues []interface{}, i int) (*ReturnBuilder, bool) {
+	if i >= len(fakeReturnValues) {
+		return nil, false
+	}
+
+	builder, ok := fakeReturnValues[i].(*ReturnBuilder)
+	return builder, ok && builder != nil
+}
+
+// The ReturnFrom() builder, if it is the only fake return value
+func computedReturns(fakeReturnValues []interface{}) (*ReturnBuilder, bool) {
+	for i := range fakeReturnValues {
+		if builder, ok := returnBuilderAt(fakeReturnValues, i); ok && builder.compute != nil {
+			if len(fakeReturnValues) != 1 {
+				panic("ReturnFrom() computes every return value, so it can't be mixed with other return values")
+			}
+			return builder, true
+		}
+	}
+
+	return nil, false
+}
+
+func (self *ReturnBuilder) isValidFor(targetType reflect.Type, returnIndex int) error {
+	if self.argIndex < 0 || self.argIndex >= targetType.NumIn() {
+		return errors.New(fmt.Sprintf("Target has no argument %d to return [target: %+v]", self.argIndex, targetType))
+	}
+
+	returned := argTypeOf(targetType)(self.argIndex)
+
+	if self.field != "" {
+		structType := returned
+		if structType.Kind() == reflect.Ptr {
+			structType = structType.Elem()
+		}
+
+		if structType.Kind() != reflect.Struct {
+			return errors.New(fmt.Sprintf("Argument %d is not a struct to return field %s of [target: %+v]", self.argIndex, self.field, targetType))
+		}
+
+		field, ok := structType.FieldByName(self.field)
+		if !ok || field.PkgPath != "" {
+			return errors.New(fmt.Sprintf("Argument %d has no exported field %s to return [type: %+v]", self.argIndex, self.field, structType))
+		}
+
+		returned = field.Type
+	}
+
+	if !returned.AssignableTo(targetType.Out(returnIndex)) {
+		return errors.New(fmt.Sprintf("Can't return %s as return value %d [returned: %+v, target: %+v]", self, returnIndex, returned, targetType))
+	}
+
+	return nil
+}
+
+func (self *ReturnBuilder) String() string {
+	if self.compute != nil {
+		return "ReturnFrom(" + FormatValue(self.compute) + ")"
+	}
+
+	if self.field != "" {
+		return fmt.Sprintf("ReturnArgField(%d, %q)", self.argIndex, self.field)
+	}
+
+	return fmt.Sprintf("ReturnArg(%d)", self.argIndex)
+}
+
+func (self *ReturnBuilder) build(args []reflect.Value, returnType reflect.Type) reflect.Value {
+	value := args[self.argIndex]
+
+	if self.field != "" {
+		if value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				panic(fmt.Sprintf("Can't return field %s of argument %d, as it is nil", self.field, self.argIndex))
+			}
+			value = value.Elem()
+		}
+		value = value.FieldByName(self.field)
+	}
+
+	result := reflect.New(returnType).Elem()
+	result.Set(value)
+	return result
+}
+
+func (self *ReturnBuilder) computeFn(targetType reflect.Type) func(args []reflect.Value) []reflect.Value {
+	return func(args []reflect.Value) []reflect.Value {
+		results, err := valuesOfTypes(self.compute(ArgList(valuesToInterfaces(args))), targetType.Out, targetType.NumOut())
+		if err != nil {
+			panic(fmt.Sprintf("ReturnFrom() computed invalid return values for %+v: %s", targetType, err.Error()))
+		}
+
+		return results
+	}
+}
+
+// Converts values to the types at each position, nil standing for the zero value
+func valuesOfTypes(values []interface{}, typeOf func(int) reflect.Type, count int) ([]reflect.Value, error) {
+	if len(values) != count {
+		return nil, errors.New(fmt.Sprintf("expected %d values, got %d", count, len(values)))
+	}
+
+	results := make([]reflect.Value, len(values))
+	for i, value := range values {
+		results[i] = reflect.New(typeOf(i)).Elem()
+
+		if value == nil {
+			continue
+		}
+
+		valueOfType := reflect.ValueOf(value)
+		if !valueOfType.Type().AssignableTo(typeOf(i)) {
+			return nil, errors.New(fmt.Sprintf("value %d is a %+v, not a %+v", i, valueOfType.Type(), typeOf(i)))
+		}
+		results[i].Set(valueOfType)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,104 @@
+package gospy_test
+
+import (
+	"errors"
+	. "github.com/cfmobile/gospy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type returnsRecord struct {
+	ID   string
+	Name string
+	note string
+}
+
+var _ = Describe("Return values computed from arguments", func() {
+	var save func(*returnsRecord) (*returnsRecord, error)
+	var rename func(returnsRecord, string) (string, error)
+	var greet func(string) string
+
+	BeforeEach(func() {
+		save = func(*returnsRecord) (*returnsRecord, error) {
+			return nil, errors.New("not saved")
+		}
+		rename = func(returnsRecord, string) (string, error) {
+			return "", errors.New("not renamed")
+		}
+		greet = func(name string) string {
+			return "hello " + name
+		}
+	})
+
+	It("should return an argument, mixed with literal values", func() {
+		spy := SpyAndFakeWithReturn(&save, ReturnArg(0), nil)
+		defer spy.Restore()
+		record := &returnsRecord{ID: "42"}
+
+		saved, err := save(record)
+		Expect(saved).To(BeIdenticalTo(record))
+		Expect(err).To(BeNil())
+	})
+
+	It("should return an argument on common signatures", func() {
+		spy := SpyAndFakeWithReturn(&greet, ReturnArg(0))
+		defer spy.Restore()
+
+		Expect(greet("ann")).To(Equal("ann"))
+		Expect(greet("bob")).To(Equal("bob"))
+	})
+
+	It("should return a field of an argument, mixed with literal values", func() {
+		spy := SpyAndFakeWithReturn(&rename, ReturnArgField(0, "ID"), errors.New("fake"))
+		defer spy.Restore()
+
+		id, err := rename(returnsRecord{ID: "42"}, "ann")
+		Expect(id).To(Equal("42"))
+		Expect(err).To(MatchError("fake"))
+	})
+
+	It("should return a field of an argument pointing to a struct", func() {
+		var find func(*returnsRecord) string
+		spy := SpyAndFakeWithReturn(&find, ReturnArgField(0, "Name"))
+		defer spy.Restore()
+
+		Expect(find(&returnsRecord{Name: "ann"})).To(Equal("ann"))
+		Expect(func() { find(nil) }).To(Panic())
+	})
+
+	It("should compute every return value", func() {
+		spy := SpyAndFakeWithReturn(&rename, ReturnFrom(func(args ArgList) []interface{} {
+			return []interface{}{args[0].(returnsRecord).ID + ":" + args[1].(string), nil}
+		}))
+		defer spy.Restore()
+
+		Expect(rename(returnsRecord{ID: "42"}, "ann")).To(Equal("42:ann"))
+	})
+
+	It("should refuse computed return values that don't fit the target", func() {
+		spy := SpyAndFakeWithReturn(&rename, ReturnFrom(func(args ArgList) []interface{} {
+			return []interface{}{42, nil}
+		}))
+		defer spy.Restore()
+
+		Expect(func() { rename(returnsRecord{}, "ann") }).To(Panic())
+	})
+
+	Context("when the builders don't fit the target", func() {
+		expectInvalid := func(fakeReturnValues ...interface{}) {
+			Expect(func() {
+				SpyAndFakeWithReturn(&rename, fakeReturnValues...).Restore()
+			}).To(Panic())
+		}
+
+		It("should refuse to build the spy", func() {
+			expectInvalid(ReturnArg(2), nil)
+			expectInvalid(ReturnArg(0), nil)
+			expectInvalid(nil, ReturnArg(1))
+			expectInvalid(ReturnArgField(1, "ID"), nil)
+			expectInvalid(ReturnArgField(0, "Missing"), nil)
+			expectInvalid(ReturnArgField(0, "note"), nil)
+			expectInvalid(ReturnFrom(func(ArgList) []interface{} { return nil }), nil)
+		})
+	})
+})
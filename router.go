@@ -0,0 +1,175 @@
+package gospy
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// Satisfied by *testing.T and friends
+type TestingT interface {
+	Name() string
+	Cleanup(func())
+}
+
+type Router struct {
+	mutex        sync.Mutex
+	spy          *GoSpy
+	targetType   reflect.Type
+	defaultFn    func(args []reflect.Value) []reflect.Value
+	routes       map[TestingT]*route
+	goroutines   map[int64]*route
+	unattributed CallList
+}
+
+type route struct {
+	spy       *GoSpy
+	fnVar     reflect.Value
+	goroutine int64
+}
+
+type routeKey struct {
+	router *Router
+}
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// Sends each call to the behaviour of the test it's attributed to: through a
+// context made by Context() among its args, or else by the goroutine that
+// registered the behaviour. Goroutines started by that one aren't attributed
+// unless they pass such a context. Calls attributed to no test run the default
+// func and are only listed by Unattributed(); no test is failed for them
+func SpyAndRoute(targetFuncPtr interface{}) *Router {
+	spy := createSpy(targetFuncPtr)
+
+	router := &Router{
+		spy:        spy,
+		targetType: spy.mock.target.Type(),
+		defaultFn:  spy.getDefaultFn(),
+		routes:     make(map[TestingT]*route),
+		goroutines: make(map[int64]*route),
+	}
+
+	spy.setTargetFn(router.dispatch, nil)
+	return router
+}
+
+func (self *Router) Spy(t TestingT) *GoSpy {
+	return self.addRoute(t, Spy)
+}
+
+func (self *Router) SpyAndFake(t TestingT) *GoSpy {
+	return self.addRoute(t, SpyAndFake)
+}
+
+func (self *Router) SpyAndFakeWithReturn(t TestingT, fakeReturnValues ...interface{}) *GoSpy {
+	return self.addRoute(t, func(fnPtr interface{}) *GoSpy {
+		return SpyAndFakeWithReturn(fnPtr, fakeReturnValues...)
+	})
+}
+
+func (self *Router) SpyAndFakeWithFunc(t TestingT, mockFunc interface{}) *GoSpy {
+	return self.addRoute(t, func(fnPtr interface{}) *GoSpy {
+		return SpyAndFakeWithFunc(fnPtr, mockFunc)
+	})
+}
+
+// Tags ctx so calls carrying it are routed to t, whichever goroutine makes them
+func (self *Router) Context(parent context.Context, t TestingT) context.Context {
+	return context.WithValue(parent, routeKey{self}, t)
+}
+
+func (self *Router) SetDefaultFunc(mockFunc interface{}) {
+	if err := mockFuncIsValid(reflect.New(self.targetType).Interface(), mockFunc); err != nil {
+		panic(err.Error())
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.defaultFn = self.spy.getFnWithMockFunc(mockFunc)
+}
+
+// The calls attributed to no test, which tests have to check for themselves
+func (self *Router) Unattributed() CallList {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	return self.unattributed
+}
+
+func (self *Router) Restore() {
+	self.spy.Restore()
+}
+
+// Each test gets a spy on a private func var, holding the original until faked
+func (self *Router) addRoute(t TestingT, spyConstructor func(fnPtr interface{}) *GoSpy) *GoSpy {
+	if err := routeOwnerIsValid(t); err != nil {
+		panic(err.Error())
+	}
+
+	fnVar := reflect.New(self.targetType)
+	fnVar.Elem().Set(reflect.MakeFunc(self.targetType, self.spy.getDefaultFn()))
+
+	newRoute := &route{spy: spyConstructor(fnVar.Interface()), fnVar: fnVar, goroutine: goroutineID()}
+
+	self.mutex.Lock()
+	if previous, ok := self.routes[t]; ok {
+		delete(self.goroutines, previous.goroutine)
+	}
+	self.routes[t] = newRoute
+	self.goroutines[newRoute.goroutine] = newRoute
+	self.mutex.Unlock()
+
+	t.Cleanup(func() {
+		self.mutex.Lock()
+		defer self.mutex.Unlock()
+
+		if self.routes[t] == newRoute {
+			delete(self.routes, t)
+			delete(self.goroutines, newRoute.goroutine)
+		}
+	})
+
+	return newRoute.spy
+}
+
+func (self *Router) dispatch(args []reflect.Value) []reflect.Value {
+	if callRoute := self.routeFor(args); callRoute != nil {
+		return callWithArgs(callRoute.fnVar.Elem(), args)
+	}
+
+	self.mutex.Lock()
+	self.unattributed = append(self.unattributed, valuesToInterfaces(args))
+	defaultFn := self.defaultFn
+	self.mutex.Unlock()
+
+	return defaultFn(args)
+}
+
+// A context among the args takes precedence over the goroutine making the call
+func (self *Router) routeFor(args []reflect.Value) *route {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	for _, arg := range args {
+		if arg.Type().Implements(contextType) && !isNil(arg) {
+			if t, ok := arg.Interface().(context.Context).Value(routeKey{self}).(TestingT); ok {
+				if callRoute, ok := self.routes[t]; ok {
+					return callRoute
+				}
+			}
+		}
+	}
+
+	return self.goroutines[goroutineID()]
+}
+
+func routeOwnerIsValid(t TestingT) error {
+	if t == nil {
+		return errors.New("Route owner can't be nil")
+	}
+
+	return nil
+}
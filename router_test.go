@@ -0,0 +1,112 @@
+package gospy_test
+
+import (
+	"context"
+	. "github.com/cfmobile/gospy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeTestingT struct {
+	name     string
+	cleanups []func()
+}
+
+func (self *fakeTestingT) Name() string {
+	return self.name
+}
+
+func (self *fakeTestingT) Cleanup(cleanup func()) {
+	self.cleanups = append(self.cleanups, cleanup)
+}
+
+func (self *fakeTestingT) finish() {
+	for i := len(self.cleanups) - 1; i >= 0; i-- {
+		self.cleanups[i]()
+	}
+}
+
+var _ = Describe("Router", func() {
+	var subject *Router
+	var fetch func(ctx context.Context, key string) string
+	var firstTest, secondTest *fakeTestingT
+
+	BeforeEach(func() {
+		fetch = func(ctx context.Context, key string) string {
+			return "original " + key
+		}
+		firstTest = &fakeTestingT{name: "first"}
+		secondTest = &fakeTestingT{name: "second"}
+
+		subject = SpyAndRoute(&fetch)
+	})
+
+	AfterEach(func() {
+		firstTest.finish()
+		secondTest.finish()
+		subject.Restore()
+	})
+
+	It("should send calls from a test's goroutine to that test's behaviour", func() {
+		spy := subject.SpyAndFakeWithReturn(firstTest, "first fake")
+
+		Expect(fetch(context.Background(), "a")).To(Equal("first fake"))
+		Expect(spy.Calls()).To(Equal(CallList{{context.Background(), "a"}}))
+	})
+
+	It("should keep the behaviour and call log of each test apart", func() {
+		firstSpy := subject.SpyAndFakeWithReturn(firstTest, "first fake")
+
+		done := make(chan string)
+		var secondSpy *GoSpy
+		go func() {
+			secondSpy = subject.SpyAndFakeWithReturn(secondTest, "second fake")
+			done <- fetch(context.Background(), "b")
+		}()
+
+		Expect(<-done).To(Equal("second fake"))
+		Expect(fetch(context.Background(), "a")).To(Equal("first fake"))
+		Expect(firstSpy.CallCount()).To(Equal(1))
+		Expect(secondSpy.CallCount()).To(Equal(1))
+	})
+
+	It("should route calls carrying a tagged context, whichever goroutine makes them", func() {
+		spy := subject.SpyAndFakeWithReturn(firstTest, "first fake")
+		ctx := subject.Context(context.Background(), firstTest)
+
+		done := make(chan string)
+		go func() {
+			done <- fetch(ctx, "a")
+		}()
+
+		Expect(<-done).To(Equal("first fake"))
+		Expect(spy.CallCount()).To(Equal(1))
+	})
+
+	It("should send calls that can't be attributed to the original and report them", func() {
+		done := make(chan string)
+		go func() {
+			done <- fetch(context.Background(), "a")
+		}()
+
+		Expect(<-done).To(Equal("original a"))
+		Expect(subject.Unattributed()).To(Equal(CallList{{context.Background(), "a"}}))
+	})
+
+	It("should use the default function for calls that can't be attributed, when set", func() {
+		subject.SetDefaultFunc(func(ctx context.Context, key string) string {
+			return "default " + key
+		})
+
+		Expect(fetch(context.Background(), "a")).To(Equal("default a"))
+	})
+
+	It("should stop routing to a test once it's cleaned up", func() {
+		spy := subject.Spy(firstTest)
+		firstTest.finish()
+
+		Expect(fetch(context.Background(), "a")).To(Equal("original a"))
+		Expect(spy.Called()).To(BeFalse())
+		Expect(subject.Unattributed()).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,297 @@
+package gospy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"reflect"
+	"time"
+)
+
+// Behaviour for named spies, loaded from a YAML or JSON file:
+//
+//	spies:
+//	  fetch:
+//	    - call: 0
+//	      return: ["first", null]
+//	    - args: ["bad"]
+//	      return: ["", "boom"]
+//	    - call: 2
+//	      delay: 200ms
+type Scenario struct {
+	path  string
+	spies []*scenarioSpy
+}
+
+type scenarioSpy struct {
+	name  string
+	line  int
+	rules []*scenarioRule
+}
+
+// Rules are tried in order. A call matches a rule if it has the rule's index
+// and its arguments match the rule's, null matching any argument
+type scenarioRule struct {
+	line    int
+	call    *int
+	args    []*yaml.Node
+	returns []*yaml.Node
+	delay   time.Duration
+
+	matchers []interface{}
+	results  []reflect.Value
+}
+
+// Reads a scenario, checking its syntax. Errors point at the file and line of the faulty entry
+func LoadScenario(path string) (*Scenario, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, errors.New(fmt.Sprintf("%s: %s", path, err.Error()))
+	}
+
+	scenario := &Scenario{path: path}
+	if len(document.Content) == 0 {
+		return scenario, nil
+	}
+
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, scenario.errorAt(root, "expected a mapping with the spies of the scenario")
+	}
+
+	for i := 0; i < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if key.Value != "spies" {
+			return nil, scenario.errorAt(key, "unknown entry %q", key.Value)
+		}
+
+		if err := scenario.parseSpies(value); err != nil {
+			return nil, err
+		}
+	}
+
+	return scenario, nil
+}
+
+// Checks the scenario against the signatures of the named spies in the
+// registry, then makes them follow it. Calls matching no rule are answered
+// as before. Nothing is bound if any spy doesn't fit
+func (self *Scenario) Bind(registry *Registry) error {
+	spies := make([]*GoSpy, len(self.spies))
+
+	for i, scenarioSpy := range self.spies {
+		spies[i] = registry.Lookup(scenarioSpy.name)
+		if spies[i] == nil {
+			return self.errorAtLine(scenarioSpy.line, "no spy named %q in the registry", scenarioSpy.name)
+		}
+
+		targetType := spies[i].mock.target.Type()
+		for _, rule := range scenarioSpy.rules {
+			if err := self.bindRule(rule, targetType); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, scenarioSpy := range self.spies {
+		spies[i].followRules(scenarioSpy.rules)
+	}
+
+	return nil
+}
+
+func (self *Scenario) parseSpies(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return self.errorAt(node, "expected a mapping of spy names to rules")
+	}
+
+	for i := 0; i < len(node.Content); i += 2 {
+		name, rules := node.Content[i], node.Content[i+1]
+		if rules.Kind != yaml.SequenceNode {
+			return self.errorAt(rules, "expected a list of rules for spy %q", name.Value)
+		}
+
+		scenarioSpy := &scenarioSpy{name: name.Value, line: name.Line}
+		for _, ruleNode := range rules.Content {
+			rule, err := self.parseRule(ruleNode)
+			if err != nil {
+				return err
+			}
+			scenarioSpy.rules = append(scenarioSpy.rules, rule)
+		}
+
+		self.spies = append(self.spies, scenarioSpy)
+	}
+
+	return nil
+}
+
+func (self *Scenario) parseRule(node *yaml.Node) (*scenarioRule, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, self.errorAt(node, "expected a rule with call, args, return or delay")
+	}
+
+	rule := &scenarioRule{line: node.Line}
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+
+		switch key.Value {
+		case "call":
+			var call int
+			if err := value.Decode(&call); err != nil || call < 0 {
+				return nil, self.errorAt(value, "call must be the index of a call, starting at 0")
+			}
+			rule.call = &call
+		case "args":
+			if value.Kind != yaml.SequenceNode {
+				return nil, self.errorAt(value, "args must be a list")
+			}
+			rule.args = value.Content
+		case "return":
+			if value.Kind != yaml.SequenceNode {
+				return nil, self.errorAt(value, "return must be a list")
+			}
+			rule.returns = value.Content
+		case "delay":
+			delay, err := time.ParseDuration(value.Value)
+			if err != nil {
+				return nil, self.errorAt(value, "invalid delay %q, expected a duration such as 200ms", value.Value)
+			}
+			rule.delay = delay
+		default:
+			return nil, self.errorAt(key, "unknown rule entry %q", key.Value)
+		}
+	}
+
+	return rule, nil
+}
+
+// Decodes the arguments and return values of the rule into the types of the target
+func (self *Scenario) bindRule(rule *scenarioRule, targetType reflect.Type) error {
+	if len(rule.args) > targetType.NumIn() {
+		return self.errorAtLine(rule.line, "%d args given, but %+v takes %d", len(rule.args), targetType, targetType.NumIn())
+	}
+
+	rule.matchers = make([]interface{}, len(rule.args))
+	for i, arg := range rule.args {
+		if arg.Tag == "!!null" {
+			rule.matchers[i] = AnyArg()
+			continue
+		}
+
+		value, err := decodeScenarioValue(arg, argTypeOf(targetType)(i))
+		if err != nil {
+			return self.errorAt(arg, "invalid argument %d for %+v: %s", i, targetType, err.Error())
+		}
+		rule.matchers[i] = Eq(value.Interface())
+	}
+
+	if rule.returns == nil {
+		return nil
+	}
+
+	if len(rule.returns) != targetType.NumOut() {
+		return self.errorAtLine(rule.line, "%d return values given, but %+v returns %d", len(rule.returns), targetType, targetType.NumOut())
+	}
+
+	rule.results = make([]reflect.Value, len(rule.returns))
+	for i, returned := range rule.returns {
+		value, err := decodeScenarioValue(returned, targetType.Out(i))
+		if err != nil {
+			return self.errorAt(returned, "invalid return value %d for %+v: %s", i, targetType, err.Error())
+		}
+		rule.results[i] = value
+	}
+
+	return nil
+}
+
+func (self *Scenario) errorAt(node *yaml.Node, format string, args ...interface{}) error {
+	return self.errorAtLine(node.Line, format, args...)
+}
+
+func (self *Scenario) errorAtLine(line int, format string, args ...interface{}) error {
+	return errors.New(fmt.Sprintf("%s:%d: %s", self.path, line, fmt.Sprintf(format, args...)))
+}
+
+// Wraps the behaviour of the spy so calls matching a rule are answered by it
+func (self *GoSpy) followRules(rules []*scenarioRule) {
+	self.mutex.Lock()
+	behaviourFor := self.behaviourFor
+	self.mutex.Unlock()
+
+	self.setBehaviourFor(func(record *callRecord) func(args []reflect.Value) []reflect.Value {
+		behaviour := behaviourFor(record)
+
+		return func(args []reflect.Value) []reflect.Value {
+			rule := matchingRule(rules, record, args)
+			if rule == nil {
+				return behaviour(args)
+			}
+
+			time.Sleep(rule.delay)
+
+			if rule.results == nil {
+				return behaviour(args)
+			}
+			return rule.results
+		}
+	}, nil)
+}
+
+func matchingRule(rules []*scenarioRule, record *callRecord, args []reflect.Value) *scenarioRule {
+	callArgs := ArgList(valuesToInterfaces(args))
+
+	for _, rule := range rules {
+		if rule.call != nil && (record == nil || record.index != *rule.call) {
+			continue
+		}
+
+		if ArgsMatch(callArgs, rule.matchers...) {
+			return rule
+		}
+	}
+
+	return nil
+}
+
+// Errors are given by their message. Other values go through JSON, so
+// structs can be written with the names of their fields
+func decodeScenarioValue(node *yaml.Node, valueType reflect.Type) (reflect.Value, error) {
+	value := reflect.New(valueType).Elem()
+
+	if node.Tag == "!!null" {
+		return value, nil
+	}
+
+	if valueType == errorType {
+		if node.Kind != yaml.ScalarNode {
+			return value, errors.New("errors must be given by their message")
+		}
+		value.Set(reflect.ValueOf(errors.New(node.Value)))
+		return value, nil
+	}
+
+	var decoded interface{}
+	if err := node.Decode(&decoded); err != nil {
+		return value, err
+	}
+
+	encoded, err := json.Marshal(decoded)
+	if err != nil {
+		return value, err
+	}
+
+	if err := json.Unmarshal(encoded, value.Addr().Interface()); err != nil {
+		return value, err
+	}
+
+	return value, nil
+}
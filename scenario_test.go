@@ -0,0 +1,172 @@
+package gospy_test
+
+import (
+	"errors"
+	. "github.com/cfmobile/gospy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type scenarioUser struct {
+	ID   int
+	Name string
+}
+
+var _ = Describe("Scenarios", func() {
+	var dir string
+	var registry *Registry
+	var fetch func(string) (string, error)
+	var findUser func(int) (*scenarioUser, error)
+
+	writeScenario := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		Expect(os.WriteFile(path, []byte(content), 0644)).To(Succeed())
+		return path
+	}
+
+	loadAndBind := func(path string) error {
+		scenario, err := LoadScenario(path)
+		if err != nil {
+			return err
+		}
+		return scenario.Bind(registry)
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "gospy-scenarios")
+		Expect(err).NotTo(HaveOccurred())
+
+		fetch = func(url string) (string, error) {
+			return "content of " + url, nil
+		}
+		findUser = func(int) (*scenarioUser, error) {
+			return nil, errors.New("real lookup")
+		}
+
+		registry = NewRegistry()
+		registry.Spy(&fetch).Named("fetch")
+		registry.SpyAndFake(&findUser).Named("findUser")
+	})
+
+	AfterEach(func() {
+		registry.RestoreAll()
+		os.RemoveAll(dir)
+	})
+
+	It("should answer calls by index and by arguments, and others as before", func() {
+		path := writeScenario("scenario.yaml", `
+spies:
+  fetch:
+    - call: 0
+      return: ["first", null]
+    - args: ["bad"]
+      return: ["", "boom"]
+`)
+		Expect(loadAndBind(path)).To(Succeed())
+
+		Expect(fetch("a")).To(Equal("first"))
+		_, err := fetch("bad")
+		Expect(err).To(MatchError("boom"))
+		Expect(fetch("c")).To(Equal("content of c"))
+	})
+
+	It("should decode values into the types of the target", func() {
+		path := writeScenario("scenario.yaml", `
+spies:
+  findUser:
+    - args: [42]
+      return: [{ID: 42, Name: ann}, null]
+`)
+		Expect(loadAndBind(path)).To(Succeed())
+
+		Expect(findUser(42)).To(Equal(&scenarioUser{ID: 42, Name: "ann"}))
+		Expect(findUser(1)).To(BeNil())
+	})
+
+	It("should delay calls", func() {
+		path := writeScenario("scenario.yaml", `
+spies:
+  fetch:
+    - call: 1
+      delay: 50ms
+`)
+		Expect(loadAndBind(path)).To(Succeed())
+
+		fetch("a")
+		start := time.Now()
+		Expect(fetch("b")).To(Equal("content of b"))
+		Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+	})
+
+	It("should load JSON scenarios", func() {
+		path := writeScenario("scenario.json", `{
+  "spies": {
+    "fetch": [
+      {"args": [null], "return": ["any", null]}
+    ]
+  }
+}`)
+		Expect(loadAndBind(path)).To(Succeed())
+
+		Expect(fetch("a")).To(Equal("any"))
+	})
+
+	Context("when the scenario is invalid", func() {
+		It("should point at the line of syntax errors", func() {
+			path := writeScenario("scenario.yaml", `
+spies:
+  fetch:
+    - call: first
+`)
+			_, err := LoadScenario(path)
+			Expect(err).To(MatchError(ContainSubstring(path + ":4:")))
+		})
+
+		It("should point at the line of unknown entries", func() {
+			path := writeScenario("scenario.yaml", `
+spies:
+  fetch:
+    - returns: ["a", null]
+`)
+			_, err := LoadScenario(path)
+			Expect(err).To(MatchError(ContainSubstring(path + `:4: unknown rule entry "returns"`)))
+		})
+
+		It("should point at spies missing from the registry", func() {
+			path := writeScenario("scenario.yaml", `
+spies:
+  fetch:
+    - return: ["a", null]
+  missing:
+    - return: []
+`)
+			Expect(loadAndBind(path)).To(MatchError(ContainSubstring(path + `:5: no spy named "missing"`)))
+			Expect(fetch("a")).To(Equal("content of a"))
+		})
+
+		It("should point at values that don't fit the signature", func() {
+			path := writeScenario("scenario.yaml", `
+spies:
+  findUser:
+    - args: [42]
+      return:
+        - {ID: forty-two}
+        - null
+`)
+			Expect(loadAndBind(path)).To(MatchError(ContainSubstring(path + ":6: invalid return value 0")))
+		})
+
+		It("should point at rules with the wrong number of values", func() {
+			path := writeScenario("scenario.yaml", `
+spies:
+  fetch:
+    - return: ["a"]
+`)
+			Expect(loadAndBind(path)).To(MatchError(ContainSubstring(path + ":4: 1 return values given")))
+		})
+	})
+})
@@ -0,0 +1,46 @@
+package gospy
+
+import (
+	"fmt"
+)
+
+// Raised by With and WithAll when their body panics, carrying the call logs
+// of the spies at the time of the panic
+type PanicWithCallLog struct {
+	Value   interface{}
+	CallLog string
+}
+
+func (self *PanicWithCallLog) Error() string {
+	return fmt.Sprintf("%v\n\nSpy call log:\n%s", self.Value, self.CallLog)
+}
+
+func (self *PanicWithCallLog) Unwrap() error {
+	err, _ := self.Value.(error)
+	return err
+}
+
+// Installs a spy for the duration of body. A nil behaviour passes calls through to the original
+func With(targetFuncPtr interface{}, behaviour Behaviour, body func(spy *GoSpy)) {
+	WithAll(func(registry *Registry) {
+		body(registry.Install(targetFuncPtr, behaviour))
+	})
+}
+
+// Restores every spy installed through the registry once body returns, panics
+// or exits its goroutine (as t.FailNow does)
+func WithAll(body func(registry *Registry)) {
+	registry := NewRegistry()
+
+	defer func() {
+		r := recover()
+		callLog := registry.String()
+		registry.RestoreAll()
+
+		if r != nil {
+			panic(&PanicWithCallLog{Value: r, CallLog: callLog})
+		}
+	}()
+
+	body(registry)
+}
@@ -0,0 +1,103 @@
+package gospy_test
+
+import (
+	. "github.com/cfmobile/gospy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"runtime"
+)
+
+var _ = Describe("Scoped spies", func() {
+	var greet func(string) string
+	var count func() int
+
+	BeforeEach(func() {
+		greet = func(name string) string {
+			return "hello " + name
+		}
+		count = func() int {
+			return 1
+		}
+	})
+
+	Describe("With", func() {
+		It("should install the spy only for the duration of the body", func() {
+			var spy *GoSpy
+
+			With(&greet, FakeWithReturn("fake"), func(s *GoSpy) {
+				spy = s
+				Expect(greet("ann")).To(Equal("fake"))
+			})
+
+			Expect(greet("ann")).To(Equal("hello ann"))
+			Expect(spy.Calls()).To(Equal(CallList{{"ann"}}))
+		})
+
+		It("should pass calls through with a nil behaviour", func() {
+			With(&greet, nil, func(s *GoSpy) {
+				Expect(greet("ann")).To(Equal("hello ann"))
+				Expect(s.Called()).To(BeTrue())
+			})
+		})
+
+		It("should restore the spy and re-raise the panic with the call log when the body panics", func() {
+			var recovered interface{}
+
+			func() {
+				defer func() {
+					recovered = recover()
+				}()
+				With(&greet, Fake(), func(s *GoSpy) {
+					greet("ann")
+					panic("test failed")
+				})
+			}()
+
+			Expect(greet("ann")).To(Equal("hello ann"))
+
+			Expect(recovered).To(BeAssignableToTypeOf(&PanicWithCallLog{}))
+			panicked := recovered.(*PanicWithCallLog)
+			Expect(panicked.Value).To(Equal("test failed"))
+			Expect(panicked.CallLog).To(ContainSubstring(`#0 ("ann")`))
+		})
+
+		It("should restore the spy when the body exits its goroutine", func() {
+			done := make(chan bool)
+
+			go func() {
+				defer close(done)
+				With(&greet, Fake(), func(s *GoSpy) {
+					runtime.Goexit()
+				})
+			}()
+
+			<-done
+			Expect(greet("ann")).To(Equal("hello ann"))
+		})
+	})
+
+	Describe("WithAll", func() {
+		It("should restore every spy installed through the registry", func() {
+			WithAll(func(registry *Registry) {
+				registry.SpyAndFakeWithReturn(&greet, "fake")
+				registry.SpyAndFakeWithReturn(&count, 2)
+
+				Expect(greet("ann")).To(Equal("fake"))
+				Expect(count()).To(Equal(2))
+				Expect(registry.Spies()).To(HaveLen(2))
+			})
+
+			Expect(greet("ann")).To(Equal("hello ann"))
+			Expect(count()).To(Equal(1))
+		})
+
+		It("should restore nested spies on the same target", func() {
+			WithAll(func(registry *Registry) {
+				registry.Spy(&greet)
+				registry.SpyAndFake(&greet)
+			})
+
+			Expect(greet("ann")).To(Equal("hello ann"))
+		})
+	})
+})
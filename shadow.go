@@ -0,0 +1,122 @@
+package gospy
+
+import (
+	"reflect"
+)
+
+type Divergence struct {
+	CallIndex     int
+	Args          ArgList
+	Original      []interface{}
+	Fake          []interface{}
+	OriginalPanic interface{}
+	FakePanic     interface{}
+}
+
+type ShadowOption func(*shadowConfig)
+
+// Reports whether the return values of the original and the fake are equivalent
+type ShadowComparer func(original, fake []interface{}) bool
+
+type shadowConfig struct {
+	returnOriginal bool
+	comparer       ShadowComparer
+}
+
+func ShadowReturnsOriginal() ShadowOption {
+	return func(config *shadowConfig) {
+		config.returnOriginal = true
+	}
+}
+
+func CompareShadowWith(comparer ShadowComparer) ShadowOption {
+	return func(config *shadowConfig) {
+		config.comparer = comparer
+	}
+}
+
+func SpyAndShadow(targetFuncPtr interface{}, mockFunc interface{}, options ...ShadowOption) *GoSpy {
+	spy := createSpy(targetFuncPtr)
+
+	if err := mockFuncIsValid(targetFuncPtr, mockFunc); err != nil {
+		panic(err.Error())
+	}
+
+	config := &shadowConfig{comparer: func(original, fake []interface{}) bool {
+		return reflect.DeepEqual(original, fake)
+	}}
+	for _, option := range options {
+		option(config)
+	}
+
+	spy.setBehaviourFor(spy.getShadowFn(spy.getDefaultFn(), spy.getFnWithMockFunc(mockFunc), config), nil)
+	spy.install(spy.newWrapper())
+	return spy
+}
+
+func (self *GoSpy) Divergences() []Divergence {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	return self.divergences
+}
+
+// Runs both the original and the fake for every call, answering with the configured one.
+// Divergences of calls made while paused have a CallIndex of -1
+func (self *GoSpy) getShadowFn(originalFn, fakeFn func([]reflect.Value) []reflect.Value, config *shadowConfig) func(record *callRecord) func([]reflect.Value) []reflect.Value {
+	return func(record *callRecord) func([]reflect.Value) []reflect.Value {
+		callIndex := -1
+		if record != nil {
+			callIndex = record.index
+		}
+
+		return func(args []reflect.Value) []reflect.Value {
+			original, originalPanic := callRecovering(originalFn, args)
+			fake, fakePanic := callRecovering(fakeFn, args)
+
+			originalValues, fakeValues := valuesToInterfaces(original), valuesToInterfaces(fake)
+			samePanic := reflect.DeepEqual(originalPanic, fakePanic)
+
+			if !samePanic || (originalPanic == nil && !config.comparer(originalValues, fakeValues)) {
+				self.mutex.Lock()
+				self.divergences = append(self.divergences, Divergence{
+					CallIndex:     callIndex,
+					Args:          valuesToInterfaces(args),
+					Original:      originalValues,
+					Fake:          fakeValues,
+					OriginalPanic: originalPanic,
+					FakePanic:     fakePanic,
+				})
+				self.mutex.Unlock()
+			}
+
+			results, panicValue := fake, fakePanic
+			if config.returnOriginal {
+				results, panicValue = original, originalPanic
+			}
+
+			if panicValue != nil {
+				panic(panicValue)
+			}
+
+			return results
+		}
+	}
+}
+
+func callRecovering(fn func([]reflect.Value) []reflect.Value, args []reflect.Value) (results []reflect.Value, panicValue interface{}) {
+	defer func() {
+		panicValue = recover()
+	}()
+
+	return fn(args), nil
+}
+
+func valuesToInterfaces(values []reflect.Value) []interface{} {
+	var result []interface{}
+	for _, value := range values {
+		result = append(result, value.Interface())
+	}
+
+	return result
+}
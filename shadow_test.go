@@ -0,0 +1,129 @@
+package gospy_test
+
+import (
+	. "github.com/cfmobile/gospy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Shadow mode", func() {
+	var subject *GoSpy
+	var square func(int) int
+	var originalCalls int
+
+	fakeSquare := func(i int) int {
+		if i > 2 {
+			return 0
+		}
+		return i * i
+	}
+
+	BeforeEach(func() {
+		originalCalls = 0
+		square = func(i int) int {
+			originalCalls++
+			return i * i
+		}
+	})
+
+	AfterEach(func() {
+		subject.Restore()
+	})
+
+	Context("when calling SpyAndShadow() with no options", func() {
+		BeforeEach(func() {
+			subject = SpyAndShadow(&square, fakeSquare)
+		})
+
+		It("should return the fake's values and still run the original", func() {
+			Expect(square(3)).To(Equal(0))
+			Expect(originalCalls).To(Equal(1))
+		})
+
+		It("should not report divergences while the fake agrees with the original", func() {
+			square(1)
+			square(2)
+
+			Expect(subject.CallCount()).To(Equal(2))
+			Expect(subject.Divergences()).To(BeEmpty())
+		})
+
+		It("should report every call where the fake disagrees with the original", func() {
+			square(1)
+			square(3)
+
+			Expect(subject.Divergences()).To(Equal([]Divergence{
+				{CallIndex: 1, Args: ArgList{3}, Original: []interface{}{9}, Fake: []interface{}{0}},
+			}))
+		})
+
+		It("should attribute divergences to the call that diverged, or -1 while paused", func() {
+			square(3)
+			subject.Pause()
+			square(4)
+			subject.Resume()
+
+			Expect(subject.Divergences()).To(HaveLen(2))
+			Expect(subject.Divergences()[0].CallIndex).To(Equal(0))
+			Expect(subject.Divergences()[1].CallIndex).To(Equal(-1))
+		})
+
+		It("should clear the divergences on Reset()", func() {
+			square(3)
+			subject.Reset()
+
+			Expect(subject.Divergences()).To(BeNil())
+		})
+	})
+
+	Context("when the original is configured to answer the calls", func() {
+		BeforeEach(func() {
+			subject = SpyAndShadow(&square, fakeSquare, ShadowReturnsOriginal())
+		})
+
+		It("should return the original's values", func() {
+			Expect(square(3)).To(Equal(9))
+			Expect(subject.Divergences()).To(HaveLen(1))
+		})
+	})
+
+	Context("when a custom comparer is used", func() {
+		BeforeEach(func() {
+			subject = SpyAndShadow(&square, fakeSquare, CompareShadowWith(func(original, fake []interface{}) bool {
+				return fake[0].(int) <= original[0].(int)
+			}))
+		})
+
+		It("should only report the calls the comparer rejects", func() {
+			square(3)
+
+			Expect(subject.Divergences()).To(BeEmpty())
+		})
+	})
+
+	Context("when only the fake panics", func() {
+		var panicked bool
+
+		BeforeEach(func() {
+			subject = SpyAndShadow(&square, func(i int) int {
+				panic("fake failure")
+			}, ShadowReturnsOriginal())
+
+			func() {
+				defer func() {
+					panicked = recover() != nil
+				}()
+				square(2)
+			}()
+		})
+
+		It("should answer with the original without panicking", func() {
+			Expect(panicked).To(BeFalse())
+		})
+
+		It("should report the panic as a divergence", func() {
+			Expect(subject.Divergences()).To(HaveLen(1))
+			Expect(subject.Divergences()[0].FakePanic).To(Equal("fake failure"))
+		})
+	})
+})
@@ -0,0 +1,125 @@
+package gospy
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+// The func var a spy is installed on, and the func it held when the spy was created
+type funcVar struct {
+	target   reflect.Value
+	original reflect.Value
+}
+
+func newFuncVar(targetFuncPtr interface{}) *funcVar {
+	target := reflect.ValueOf(targetFuncPtr).Elem()
+	original := reflect.New(target.Type()).Elem()
+	original.Set(target)
+
+	return &funcVar{target: target, original: original}
+}
+
+func (self *funcVar) replace(fn interface{}) {
+	self.target.Set(reflect.ValueOf(fn))
+}
+
+// Spies installed on the same func var, from the outermost to the innermost.
+// Only the innermost is stored in the var, and each one delegates outward.
+type targetStack struct {
+	original reflect.Value
+	spies    []*GoSpy
+}
+
+var targetStacks = struct {
+	sync.Mutex
+	stacks map[uintptr]*targetStack
+}{stacks: make(map[uintptr]*targetStack)}
+
+func (self *GoSpy) install(wrapper reflect.Value) {
+	targetStacks.Lock()
+	defer targetStacks.Unlock()
+
+	target := self.mock.target
+	key := target.UnsafeAddr()
+
+	// A var reassigned since its last spy was installed starts a new stack, abandoning the leaked spies
+	stack, ok := targetStacks.stacks[key]
+	if !ok || funcWord(target) != stack.spies[len(stack.spies)-1].installed {
+		stack = &targetStack{original: self.mock.original}
+		targetStacks.stacks[key] = stack
+	}
+
+	self.mock.replace(wrapper.Interface())
+	self.wrapper = wrapper
+	self.installed = funcWord(target)
+	stack.spies = append(stack.spies, self)
+}
+
+func (self *GoSpy) uninstall() error {
+	targetStacks.Lock()
+	defer targetStacks.Unlock()
+
+	target := self.mock.target
+	key := target.UnsafeAddr()
+
+	stack, ok := targetStacks.stacks[key]
+	position := -1
+	if ok {
+		position = stack.indexOf(self)
+	}
+
+	if position < 0 {
+		return nil // Never installed, or already restored
+	}
+
+	innermost := stack.spies[len(stack.spies)-1]
+	if funcWord(target) != innermost.installed {
+		return errors.New(fmt.Sprintf("Can't restore spy on %+v: the target was reassigned outside of gospy while being spied on. Restore every spy before assigning the target directly", target.Type()))
+	}
+
+	stack.spies = append(stack.spies[:position], stack.spies[position+1:]...)
+
+	switch {
+	case len(stack.spies) == 0:
+		self.mock.replace(stack.original.Interface())
+		delete(targetStacks.stacks, key)
+	case position == len(stack.spies):
+		self.mock.replace(stack.spies[position-1].wrapper.Interface())
+	}
+
+	return nil
+}
+
+// The function this spy delegates to: the next spy outward, or the original
+func (self *GoSpy) outer() reflect.Value {
+	targetStacks.Lock()
+	defer targetStacks.Unlock()
+
+	if stack, ok := targetStacks.stacks[self.mock.target.UnsafeAddr()]; ok {
+		if position := stack.indexOf(self); position > 0 {
+			return stack.spies[position-1].wrapper
+		} else if position == 0 {
+			return stack.original
+		}
+	}
+
+	return self.mock.original
+}
+
+func (self *targetStack) indexOf(spy *GoSpy) int {
+	for i, installed := range self.spies {
+		if installed == spy {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Identifies the closure held by a func var, as func values can't be compared
+func funcWord(target reflect.Value) uintptr {
+	return *(*uintptr)(unsafe.Pointer(target.UnsafeAddr()))
+}
@@ -0,0 +1,148 @@
+package gospy_test
+
+import (
+	. "github.com/cfmobile/gospy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Nested spies", func() {
+	var greet func(string) string
+	var outer, inner *GoSpy
+
+	BeforeEach(func() {
+		greet = func(name string) string {
+			return "hello " + name
+		}
+	})
+
+	Context("when two pass-through spies are installed on the same target", func() {
+		BeforeEach(func() {
+			outer = Spy(&greet)
+			inner = Spy(&greet)
+		})
+
+		AfterEach(func() {
+			inner.Restore()
+			outer.Restore()
+		})
+
+		It("should let both spies see every call", func() {
+			Expect(greet("ann")).To(Equal("hello ann"))
+
+			Expect(inner.Calls()).To(Equal(CallList{{"ann"}}))
+			Expect(outer.Calls()).To(Equal(CallList{{"ann"}}))
+		})
+	})
+
+	Context("when the inner spy fakes the target", func() {
+		BeforeEach(func() {
+			outer = Spy(&greet)
+			inner = SpyAndFakeWithReturn(&greet, "fake")
+		})
+
+		AfterEach(func() {
+			inner.Restore()
+			outer.Restore()
+		})
+
+		It("should answer the calls without delegating outward", func() {
+			Expect(greet("ann")).To(Equal("fake"))
+
+			Expect(inner.CallCount()).To(Equal(1))
+			Expect(outer.CallCount()).To(BeZero())
+		})
+	})
+
+	Context("when the spies are restored in the order they were installed", func() {
+		BeforeEach(func() {
+			outer = SpyAndFakeWithReturn(&greet, "outer fake")
+			inner = Spy(&greet)
+
+			outer.Restore()
+		})
+
+		It("should keep the inner spy monitoring, delegating to the original", func() {
+			Expect(greet("ann")).To(Equal("hello ann"))
+
+			Expect(inner.CallCount()).To(Equal(1))
+			Expect(outer.CallCount()).To(BeZero())
+		})
+
+		It("should leave the original function once the inner spy is restored too", func() {
+			inner.Restore()
+
+			Expect(greet("ann")).To(Equal("hello ann"))
+			Expect(inner.CallCount()).To(BeZero())
+		})
+	})
+
+	Context("when the spies are restored innermost first", func() {
+		BeforeEach(func() {
+			outer = SpyAndFakeWithReturn(&greet, "outer fake")
+			inner = Spy(&greet)
+
+			inner.Restore()
+		})
+
+		AfterEach(func() {
+			outer.Restore()
+		})
+
+		It("should put the outer spy back in the target", func() {
+			Expect(greet("ann")).To(Equal("outer fake"))
+
+			Expect(outer.CallCount()).To(Equal(1))
+			Expect(inner.CallCount()).To(BeZero())
+		})
+	})
+
+	Context("when a spy is restored twice", func() {
+		BeforeEach(func() {
+			outer = Spy(&greet)
+			inner = SpyAndFakeWithReturn(&greet, "fake")
+
+			outer.Restore()
+			outer.Restore()
+		})
+
+		AfterEach(func() {
+			inner.Restore()
+		})
+
+		It("should not affect the remaining spies", func() {
+			Expect(greet("ann")).To(Equal("fake"))
+		})
+	})
+
+	Context("when the target is reassigned while being spied on", func() {
+		var panicked bool
+
+		BeforeEach(func() {
+			outer = Spy(&greet)
+			greet = func(string) string { return "reassigned" }
+
+			func() {
+				defer func() {
+					panicked = recover() != nil
+				}()
+				outer.Restore()
+			}()
+		})
+
+		It("should refuse to restore with a diagnostic", func() {
+			Expect(panicked).To(BeTrue())
+		})
+
+		It("should not overwrite the reassigned function", func() {
+			Expect(greet("ann")).To(Equal("reassigned"))
+		})
+
+		It("should let new spies be installed from scratch", func() {
+			spy := Spy(&greet)
+			spy.Restore()
+
+			Expect(greet("ann")).To(Equal("reassigned"))
+		})
+	})
+})
@@ -0,0 +1,228 @@
+package gospy
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// A fake spread over several targets, answering each call according to the
+// state the calls before it left the machine in
+type StateMachine struct {
+	mutex       sync.Mutex
+	state       string
+	spies       []*GoSpy
+	transitions []*Transition
+	rejections  []string
+}
+
+// A call expected in a state, how to answer it and the state it leads to
+type Transition struct {
+	machine  *StateMachine
+	from     string
+	to       string
+	spy      *GoSpy
+	matchers []interface{}
+	returns  func(args []reflect.Value) []reflect.Value
+}
+
+func NewStateMachine(initialState string) *StateMachine {
+	return &StateMachine{state: initialState}
+}
+
+// Expects calls to the target with arguments matching matchers in state. The
+// target is faked by the machine from the first transition on it. Transitions
+// are tried in the order they were added
+func (self *StateMachine) On(state string, targetFuncPtr interface{}, matchers ...interface{}) *Transition {
+	spy := self.spyOn(targetFuncPtr)
+	targetType := spy.mock.target.Type()
+
+	if len(matchers) > targetType.NumIn() {
+		panic(fmt.Sprintf("Too many argument matchers for the target [target: %+v, matchers: %d]", targetType, len(matchers)))
+	}
+
+	transition := &Transition{
+		machine:  self,
+		from:     state,
+		to:       state,
+		spy:      spy,
+		matchers: matchers,
+		returns:  spy.getFnWithReturnValues(nil),
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.transitions = append(self.transitions, transition)
+	return transition
+}
+
+// Answers the call with these values, or builders as accepted by SpyAndFakeWithReturn().
+// Values that don't fit the target panic, and the transition is dropped
+func (self *Transition) Return(fakeReturnValues ...interface{}) *Transition {
+	defer func() {
+		if r := recover(); r != nil {
+			self.machine.remove(self)
+			panic(r)
+		}
+	}()
+
+	returns := self.spy.getFnWithReturnValues(fakeReturnValues)
+
+	self.machine.mutex.Lock()
+	defer self.machine.mutex.Unlock()
+
+	self.returns = returns
+	return self
+}
+
+// Moves the machine to state once the call is answered. Without it, the machine stays in the same state
+func (self *Transition) GoTo(state string) *Transition {
+	self.machine.mutex.Lock()
+	defer self.machine.mutex.Unlock()
+
+	self.to = state
+	return self
+}
+
+func (self *StateMachine) State() string {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	return self.state
+}
+
+// The calls that were not expected in the state the machine was in, described
+func (self *StateMachine) Rejections() []string {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	return append([]string(nil), self.rejections...)
+}
+
+// The spy faking the target, for assertions on its calls
+func (self *StateMachine) Spy(targetFuncPtr interface{}) *GoSpy {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	if spy := self.spyFor(targetFuncPtr); spy != nil {
+		return spy
+	}
+
+	panic(fmt.Sprintf("The state machine has no transitions on %+v", reflect.TypeOf(targetFuncPtr)))
+}
+
+// Restores the spies of the machine, innermost first
+func (self *StateMachine) Restore() {
+	self.mutex.Lock()
+	spies := self.spies
+	self.spies = nil
+	self.mutex.Unlock()
+
+	for i := len(spies) - 1; i >= 0; i-- {
+		spies[i].Restore()
+	}
+}
+
+func (self *StateMachine) remove(transition *Transition) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	for i, candidate := range self.transitions {
+		if candidate == transition {
+			self.transitions = append(self.transitions[:i:i], self.transitions[i+1:]...)
+			return
+		}
+	}
+}
+
+func (self *StateMachine) spyOn(targetFuncPtr interface{}) *GoSpy {
+	if err := targetIsValid(targetFuncPtr); err != nil {
+		panic(err.Error())
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	if spy := self.spyFor(targetFuncPtr); spy != nil {
+		return spy
+	}
+
+	spy := createSpy(targetFuncPtr)
+	spy.setTargetFn(func(args []reflect.Value) []reflect.Value {
+		return self.answer(spy, args)
+	}, nil)
+
+	self.spies = append(self.spies, spy)
+	return spy
+}
+
+// Must be called with the mutex held
+func (self *StateMachine) spyFor(targetFuncPtr interface{}) *GoSpy {
+	for _, spy := range self.spies {
+		if spy.mock.target.UnsafeAddr() == reflect.ValueOf(targetFuncPtr).Pointer() {
+			return spy
+		}
+	}
+
+	return nil
+}
+
+// Takes the first transition matching the call, atomically, then answers it
+func (self *StateMachine) answer(spy *GoSpy, args []reflect.Value) []reflect.Value {
+	callArgs := ArgList(valuesToInterfaces(args))
+
+	self.mutex.Lock()
+	transition := self.transitionFor(spy, callArgs)
+	if transition == nil {
+		err := self.rejection(spy, callArgs)
+		self.rejections = append(self.rejections, err.Error())
+		self.mutex.Unlock()
+		panic(err.Error())
+	}
+
+	self.state = transition.to
+	returns := transition.returns
+	self.mutex.Unlock()
+
+	captureArgs(callArgs, transition.matchers)
+	return returns(args)
+}
+
+// Must be called with the mutex held
+func (self *StateMachine) transitionFor(spy *GoSpy, args ArgList) *Transition {
+	for _, transition := range self.transitions {
+		if transition.from == self.state && transition.spy == spy && ArgsMatch(args, transition.matchers...) {
+			return transition
+		}
+	}
+
+	return nil
+}
+
+// Must be called with the mutex held
+func (self *StateMachine) rejection(spy *GoSpy, args ArgList) error {
+	var expected []string
+	for _, transition := range self.transitions {
+		if transition.from == self.state {
+			expected = append(expected, "\n  "+transition.String())
+		}
+	}
+
+	if len(expected) == 0 {
+		expected = append(expected, " none")
+	}
+
+	return errors.New(fmt.Sprintf("Unexpected call to %s%s in state %q. Expected calls:%s", spy.Name(), FormatArgs(args), self.state, strings.Join(expected, "")))
+}
+
+func (self *Transition) String() string {
+	description := self.spy.Name() + FormatArgs(self.matchers)
+	if self.to != self.from {
+		description += fmt.Sprintf(" -> %q", self.to)
+	}
+
+	return description
+}
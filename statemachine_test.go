@@ -0,0 +1,111 @@
+package gospy_test
+
+import (
+	"errors"
+	. "github.com/cfmobile/gospy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("State machine fakes", func() {
+	var connect func(string) error
+	var auth func(string, string) error
+	var send func(string) (int, error)
+	var disconnect func() error
+	var machine *StateMachine
+
+	BeforeEach(func() {
+		connect = func(string) error { return errors.New("real connect") }
+		auth = func(string, string) error { return errors.New("real auth") }
+		send = func(string) (int, error) { return 0, errors.New("real send") }
+		disconnect = func() error { return errors.New("real disconnect") }
+
+		machine = NewStateMachine("disconnected")
+		machine.On("disconnected", &connect, AnyArg()).GoTo("connected")
+		machine.On("connected", &auth, "ann", "secret").GoTo("authenticated")
+		machine.On("connected", &auth, AnyArg(), AnyArg()).Return(errors.New("denied"))
+		machine.On("authenticated", &send, AnyArg()).Return(ReturnFrom(func(args ArgList) []interface{} {
+			return []interface{}{len(args[0].(string)), nil}
+		}))
+		machine.On("authenticated", &disconnect).GoTo("disconnected")
+	})
+
+	AfterEach(func() {
+		machine.Restore()
+	})
+
+	It("should answer the calls made in order and follow the transitions", func() {
+		Expect(connect("db:5432")).To(Succeed())
+		Expect(auth("ann", "wrong")).To(MatchError("denied"))
+		Expect(machine.State()).To(Equal("connected"))
+		Expect(auth("ann", "secret")).To(Succeed())
+		Expect(send("hello")).To(Equal(5))
+		Expect(send("hi")).To(Equal(2))
+		Expect(disconnect()).To(Succeed())
+
+		Expect(machine.State()).To(Equal("disconnected"))
+		Expect(machine.Rejections()).To(BeEmpty())
+	})
+
+	It("should share the state between the spies of the machine", func() {
+		connect("db:5432")
+		auth("ann", "secret")
+
+		Expect(machine.Spy(&connect).Calls()).To(Equal(CallList{{"db:5432"}}))
+		Expect(machine.Spy(&auth).Calls()).To(Equal(CallList{{"ann", "secret"}}))
+		Expect(machine.State()).To(Equal("authenticated"))
+	})
+
+	It("should reject out of order calls with a description of the expected ones", func() {
+		var rejection interface{}
+		func() {
+			defer func() {
+				rejection = recover()
+			}()
+			send("hello")
+		}()
+
+		Expect(rejection).To(ContainSubstring(`("hello") in state "disconnected"`))
+		Expect(rejection).To(ContainSubstring(`(AnyArg()) -> "connected"`))
+		Expect(machine.Rejections()).To(Equal([]string{rejection.(string)}))
+		Expect(machine.State()).To(Equal("disconnected"))
+	})
+
+	It("should only capture from the transition answering the call", func() {
+		login := func(string, string) error { return nil }
+		accepted, denied := Captor[string](), Captor[string]()
+
+		logins := NewStateMachine("ready")
+		defer logins.Restore()
+		logins.On("ready", &login, accepted, "secret").GoTo("logged in")
+		logins.On("ready", &login, denied, AnyArg()).Return(errors.New("denied"))
+
+		login("bob", "wrong")
+		login("ann", "secret")
+
+		Expect(accepted.All()).To(Equal([]string{"ann"}))
+		Expect(denied.All()).To(Equal([]string{"bob"}))
+	})
+
+	It("should restore the targets", func() {
+		machine.Restore()
+
+		Expect(connect("db:5432")).To(MatchError("real connect"))
+		Expect(disconnect()).To(MatchError("real disconnect"))
+	})
+
+	It("should refuse transitions that don't fit the target", func() {
+		Expect(func() { machine.On("connected", &connect, "a", "b") }).To(Panic())
+		Expect(func() { machine.On("connected", &send, AnyArg()).Return(42) }).To(Panic())
+
+		connect("db:5432")
+		Expect(func() { send("hello") }).To(Panic())
+		Expect(machine.Rejections()).To(HaveLen(1))
+	})
+
+	It("should refuse to return spies for targets without transitions", func() {
+		var other func()
+
+		Expect(func() { machine.Spy(&other) }).To(Panic())
+	})
+})
@@ -0,0 +1,200 @@
+package gospy
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+type StructCall struct {
+	Field string
+	Args  ArgList
+}
+
+type StructSpy struct {
+	fields []string
+	spies  map[string]*GoSpy
+}
+
+type StructSpyOption func(*structSpyConfig)
+
+type structSpyConfig struct {
+	fakeAll     bool
+	passThrough map[string]bool
+	fakes       map[string]func(fieldPtr interface{}) *GoSpy
+}
+
+// Fakes every func field with zero return values, unless overridden per field
+func FakeAllFields() StructSpyOption {
+	return func(config *structSpyConfig) {
+		config.fakeAll = true
+	}
+}
+
+func PassThroughField(name string) StructSpyOption {
+	return func(config *structSpyConfig) {
+		config.passThrough[name] = true
+	}
+}
+
+func FakeField(name string, fakeReturnValues ...interface{}) StructSpyOption {
+	return func(config *structSpyConfig) {
+		config.fakes[name] = func(fieldPtr interface{}) *GoSpy {
+			return SpyAndFakeWithReturn(fieldPtr, fakeReturnValues...)
+		}
+	}
+}
+
+func FakeFieldWithFunc(name string, mockFunc interface{}) StructSpyOption {
+	return func(config *structSpyConfig) {
+		config.fakes[name] = func(fieldPtr interface{}) *GoSpy {
+			return SpyAndFakeWithFunc(fieldPtr, mockFunc)
+		}
+	}
+}
+
+func SpyStruct(targetStructPtr interface{}, options ...StructSpyOption) *StructSpy {
+	if err := structTargetIsValid(targetStructPtr); err != nil {
+		panic(err.Error())
+	}
+
+	config := &structSpyConfig{passThrough: make(map[string]bool), fakes: make(map[string]func(interface{}) *GoSpy)}
+	for _, option := range options {
+		option(config)
+	}
+
+	structValue := reflect.ValueOf(targetStructPtr).Elem()
+	funcFields := spiableFields(structValue.Type())
+
+	if err := structConfigIsValid(config, funcFields); err != nil {
+		panic(err.Error())
+	}
+
+	structSpy := &StructSpy{spies: make(map[string]*GoSpy)}
+
+	// Leaves no field modified if one of the fakes can't be installed
+	defer func() {
+		if r := recover(); r != nil {
+			structSpy.RestoreAll()
+			panic(r)
+		}
+	}()
+
+	for _, name := range funcFields {
+		fieldPtr := structValue.FieldByName(name).Addr().Interface()
+
+		var spy *GoSpy
+		if fake, ok := config.fakes[name]; ok {
+			spy = fake(fieldPtr)
+		} else if config.fakeAll && !config.passThrough[name] {
+			spy = SpyAndFake(fieldPtr)
+		} else {
+			spy = Spy(fieldPtr)
+		}
+
+		structSpy.addSpy(name, spy)
+	}
+
+	return structSpy
+}
+
+func (self *StructSpy) Fields() []string {
+	return self.fields
+}
+
+func (self *StructSpy) Spy(fieldName string) *GoSpy {
+	spy, ok := self.spies[fieldName]
+	if !ok {
+		panic(fmt.Sprintf("No spy installed on field %q", fieldName))
+	}
+
+	return spy
+}
+
+// The calls kept by the spy of each field, ordered by sequence number
+func (self *StructSpy) Calls() []StructCall {
+	var entries []RecordedCall
+	for _, name := range self.fields {
+		for _, call := range self.spies[name].Query() {
+			entries = append(entries, RecordedCall{Spy: name, Call: call})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Call.Seq < entries[j].Call.Seq
+	})
+
+	var calls []StructCall
+	for _, entry := range entries {
+		calls = append(calls, StructCall{Field: entry.Spy, Args: entry.Call.Args})
+	}
+
+	return calls
+}
+
+func (self *StructSpy) ResetAll() {
+	for _, name := range self.fields {
+		self.spies[name].Reset()
+	}
+}
+
+func (self *StructSpy) RestoreAll() {
+	for _, name := range self.fields {
+		self.spies[name].Restore()
+	}
+}
+
+func (self *StructSpy) addSpy(name string, spy *GoSpy) {
+	self.fields = append(self.fields, name)
+	self.spies[name] = spy
+}
+
+// Exported func-typed fields, in declaration order
+func spiableFields(structType reflect.Type) []string {
+	var fields []string
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.PkgPath == "" && field.Type.Kind() == reflect.Func {
+			fields = append(fields, field.Name)
+		}
+	}
+
+	return fields
+}
+
+func structTargetIsValid(target interface{}) error {
+	if target == nil {
+		return errors.New("Target struct can't be nil")
+	}
+
+	targetValue := reflect.ValueOf(target)
+	isStructPtr := targetValue.Kind() == reflect.Ptr && !targetValue.IsNil() && targetValue.Elem().Kind() == reflect.Struct
+
+	if !isStructPtr {
+		return errors.New(fmt.Sprintf("SpyStruct target has to be the pointer to a struct [type: %+v]", targetValue.Type()))
+	}
+
+	return nil
+}
+
+func structConfigIsValid(config *structSpyConfig, funcFields []string) error {
+	known := make(map[string]bool)
+	for _, name := range funcFields {
+		known[name] = true
+	}
+
+	for name := range config.fakes {
+		if !known[name] {
+			return errors.New(fmt.Sprintf("Can't fake field %q: not an exported func field of the target struct", name))
+		}
+	}
+
+	for name := range config.passThrough {
+		if !known[name] {
+			return errors.New(fmt.Sprintf("Can't pass through field %q: not an exported func field of the target struct", name))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,246 @@
+package gospy_test
+
+import (
+	"errors"
+	. "github.com/cfmobile/gospy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"sync"
+	"time"
+)
+
+type dependencies struct {
+	Fetch  func(id int) (string, error)
+	Store  func(id int, value string) error
+	Name   string
+	hidden func()
+}
+
+var _ = Describe("StructSpy", func() {
+	var subject *StructSpy
+	var deps dependencies
+	var panicked bool
+
+	fetchError := errors.New("fetch error")
+
+	BeforeEach(func() {
+		subject = nil
+		panicked = false
+		deps = dependencies{
+			Fetch: func(id int) (string, error) {
+				return "original", nil
+			},
+			Store: func(id int, value string) error {
+				return nil
+			},
+			Name:   "deps",
+			hidden: func() {},
+		}
+	})
+
+	AfterEach(func() {
+		if subject != nil {
+			subject.RestoreAll()
+		}
+	})
+
+	panicRecover := func() {
+		panicked = recover() != nil
+	}
+
+	Context("when calling SpyStruct() with a valid struct pointer and no options", func() {
+		BeforeEach(func() {
+			subject = SpyStruct(&deps)
+		})
+
+		It("should install a spy on every exported func field, in declaration order", func() {
+			Expect(subject.Fields()).To(Equal([]string{"Fetch", "Store"}))
+		})
+
+		It("should not have affected the behaviour of the fields", func() {
+			value, err := deps.Fetch(1)
+
+			Expect(value).To(Equal("original"))
+			Expect(err).To(BeNil())
+		})
+
+		It("should record the calls on the spy of each field", func() {
+			deps.Fetch(1)
+			deps.Store(2, "two")
+
+			Expect(subject.Spy("Fetch").Calls()).To(Equal(CallList{{1}}))
+			Expect(subject.Spy("Store").Calls()).To(Equal(CallList{{2, "two"}}))
+		})
+
+		It("should keep a combined log of the calls in the order they were made", func() {
+			deps.Store(2, "two")
+			deps.Fetch(1)
+			deps.Store(3, "three")
+
+			Expect(subject.Calls()).To(Equal([]StructCall{
+				{Field: "Store", Args: ArgList{2, "two"}},
+				{Field: "Fetch", Args: ArgList{1}},
+				{Field: "Store", Args: ArgList{3, "three"}},
+			}))
+		})
+
+		It("should keep the combined log in call order when calls are recorded out of order", func() {
+			subject.Spy("Fetch").SetRecording(RecordOnly(func(args ArgList) bool {
+				if args[0].(int)%2 == 0 {
+					time.Sleep(time.Millisecond)
+				}
+				return true
+			}))
+
+			var wait sync.WaitGroup
+			for i := 0; i < 50; i++ {
+				wait.Add(1)
+				go func(i int) {
+					defer wait.Done()
+					deps.Fetch(i)
+				}(i)
+			}
+			wait.Wait()
+
+			calls := subject.Calls()
+			Expect(calls).To(HaveLen(50))
+			for i, call := range calls {
+				Expect(call).To(Equal(StructCall{Field: "Fetch", Args: subject.Spy("Fetch").Calls()[i]}))
+			}
+		})
+
+		It("should only list the calls the spies of the fields still keep", func() {
+			subject.Spy("Fetch").SetRecording(KeepLast(1))
+			deps.Fetch(1)
+			deps.Store(2, "two")
+			deps.Fetch(3)
+
+			Expect(subject.Calls()).To(Equal([]StructCall{
+				{Field: "Store", Args: ArgList{2, "two"}},
+				{Field: "Fetch", Args: ArgList{3}},
+			}))
+
+			subject.Spy("Store").Reset()
+			Expect(subject.Calls()).To(Equal([]StructCall{{Field: "Fetch", Args: ArgList{3}}}))
+		})
+
+		It("should panic when asking for the spy of a field that isn't spied", func() {
+			func() {
+				defer panicRecover()
+				subject.Spy("Name")
+			}()
+
+			Expect(panicked).To(BeTrue())
+		})
+
+		Context("when ResetAll() is called", func() {
+			BeforeEach(func() {
+				deps.Fetch(1)
+				deps.Store(2, "two")
+				subject.ResetAll()
+			})
+
+			It("should clear every spy and the combined log", func() {
+				Expect(subject.Spy("Fetch").Called()).To(BeFalse())
+				Expect(subject.Spy("Store").Called()).To(BeFalse())
+				Expect(subject.Calls()).To(BeNil())
+			})
+		})
+
+		Context("when RestoreAll() is called", func() {
+			BeforeEach(func() {
+				subject.RestoreAll()
+			})
+
+			It("should no longer monitor calls to any field", func() {
+				deps.Fetch(1)
+
+				Expect(subject.Spy("Fetch").Called()).To(BeFalse())
+				Expect(subject.Calls()).To(BeNil())
+			})
+		})
+	})
+
+	Context("when calling SpyStruct() with options", func() {
+		BeforeEach(func() {
+			subject = SpyStruct(&deps, FakeAllFields(), FakeField("Fetch", "fake", fetchError))
+		})
+
+		It("should fake the fields with specific fakes", func() {
+			value, err := deps.Fetch(1)
+
+			Expect(value).To(Equal("fake"))
+			Expect(err).To(Equal(fetchError))
+		})
+
+		It("should fake the remaining fields with zero values", func() {
+			Expect(deps.Store(1, "one")).To(BeNil())
+			Expect(subject.Spy("Store").CallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when a field is passed through while faking all fields", func() {
+		BeforeEach(func() {
+			subject = SpyStruct(&deps, FakeAllFields(), PassThroughField("Fetch"))
+		})
+
+		It("should keep the original behaviour for that field", func() {
+			value, _ := deps.Fetch(1)
+
+			Expect(value).To(Equal("original"))
+		})
+	})
+
+	Context("when a field is faked with a function", func() {
+		BeforeEach(func() {
+			subject = SpyStruct(&deps, FakeFieldWithFunc("Fetch", func(id int) (string, error) {
+				return "from func", nil
+			}))
+		})
+
+		It("should use the function as the field's behaviour", func() {
+			value, _ := deps.Fetch(1)
+
+			Expect(value).To(Equal("from func"))
+		})
+	})
+
+	Context("when an option names a field that can't be spied", func() {
+		BeforeEach(func() {
+			defer panicRecover()
+			subject = SpyStruct(&deps, FakeField("hidden"))
+		})
+
+		It("should have panicked", func() {
+			Expect(panicked).To(BeTrue())
+		})
+	})
+
+	Context("when a fake can't be installed on one of the fields", func() {
+		BeforeEach(func() {
+			defer panicRecover()
+			subject = SpyStruct(&deps, FakeField("Store", "not an error"))
+		})
+
+		It("should have panicked", func() {
+			Expect(panicked).To(BeTrue())
+		})
+
+		It("should leave every field with its original behaviour", func() {
+			value, _ := deps.Fetch(1)
+
+			Expect(value).To(Equal("original"))
+		})
+	})
+
+	Context("when calling SpyStruct() with something other than a struct pointer", func() {
+		BeforeEach(func() {
+			defer panicRecover()
+			subject = SpyStruct(deps)
+		})
+
+		It("should have panicked", func() {
+			Expect(panicked).To(BeTrue())
+		})
+	})
+})